@@ -19,12 +19,14 @@ func init() {
 	rootCmd.PersistentFlags().String("aws.session_token", "", "AWS session token")
 	rootCmd.PersistentFlags().Bool("aws.dynamodb.enabled", false, "Store measurements to AWS DynamoDB")
 	rootCmd.PersistentFlags().String("aws.dynamodb.table", "", "AWS DynamoDB table name")
+	rootCmd.PersistentFlags().Bool("aws.dynamodb.diagnostics", false, "Store RSSI and raw advertisement data to AWS DynamoDB")
 	rootCmd.PersistentFlags().Bool("aws.sqs.enabled", false, "Send measurements to AWS SQS")
 	rootCmd.PersistentFlags().String("aws.sqs.queue.name", "", "AWS SQS queue name")
 	rootCmd.PersistentFlags().String("aws.sqs.queue.url", "", "AWS SQS queue URL")
+	rootCmd.PersistentFlags().Bool("aws.sqs.diagnostics", false, "Include RSSI and raw advertisement data in AWS SQS messages")
 }
 
-func addDynamoDBExporter(exporters *[]exporter.Exporter) error {
+func addDynamoDBExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	table := viper.GetString("aws.dynamodb.table")
 	if table == "" {
 		return fmt.Errorf("DynamoDB table name must be specified")
@@ -39,11 +41,11 @@ func addDynamoDBExporter(exporters *[]exporter.Exporter) error {
 	if err != nil {
 		return err
 	}
-	*exporters = append(*exporters, exp)
+	*exporters = append(*exporters, withDiagnostics(exp, "aws.dynamodb.diagnostics"))
 	return nil
 }
 
-func addSQSExporter(exporters *[]exporter.Exporter) error {
+func addSQSExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	queueName := viper.GetString("aws.sqs.queue.name")
 	queueURL := viper.GetString("aws.sqs.queue.url")
 	if queueName == "" && queueURL == "" {
@@ -60,6 +62,6 @@ func addSQSExporter(exporters *[]exporter.Exporter) error {
 	if err != nil {
 		return err
 	}
-	*exporters = append(*exporters, exp)
+	*exporters = append(*exporters, withDiagnostics(exp, "aws.sqs.diagnostics"))
 	return nil
 }
@@ -4,10 +4,10 @@ package cmd
 
 import "github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 
-func addDynamoDBExporter(exporters *[]exporter.Exporter) error {
+func addDynamoDBExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	return ErrNotEnabled
 }
 
-func addSQSExporter(exporters *[]exporter.Exporter) error {
+func addSQSExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	return ErrNotEnabled
 }
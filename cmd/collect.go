@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -19,6 +20,13 @@ var collectCmd = &cobra.Command{
 		logger.Info("Starting ruuvitag-gollector")
 		scn := scanner.NewOnce(logger, peripherals)
 		scn.Exporters = exporters
+		scn.SetDecryptionKeys(keys)
+		scn.SetCalibrations(calibrations)
+		scn.SetDerivedMetrics(metrics)
+		scn.SetDeduplicator(dedup)
+		scn.SetCollectAll(collectAll)
+		scn.SetOutlierFilter(outliers)
+		scn.SetQuarantineExporters(quarantine)
 		return runOnce(scn)
 	},
 }
@@ -28,14 +36,19 @@ func init() {
 }
 
 func runOnce(scn *scanner.OnceScanner) error {
-	if err := scn.Init(device); err != nil {
+	if err := setupRecording(scn.Engine); err != nil {
+		return err
+	}
+	defer closeRecording()
+	if err := scn.Init(devices...); err != nil {
 		return err
 	}
 	logger.Info("Scanning once")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
 	go func() {
 		<-interrupt
 		cancel()
@@ -44,6 +57,5 @@ func runOnce(scn *scanner.OnceScanner) error {
 		return fmt.Errorf("failed to scan: %w", err)
 	}
 	logger.Info("Stopping scanner")
-	scn.Close()
-	return nil
+	return scn.Close()
 }
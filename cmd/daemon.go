@@ -2,13 +2,19 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/niktheblak/ruuvitag-gollector/pkg/aggregate"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/cron"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
 )
 
@@ -18,13 +24,52 @@ var daemonCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger.Info("Starting ruuvitag-gollector")
 		interval := viper.GetDuration("interval")
-		if interval > 0 {
+		cronSpec := viper.GetString("cron")
+		if cronSpec != "" || interval > 0 {
+			funcs, err := aggregate.ParseFuncs(viper.GetStringSlice("aggregate"))
+			if err != nil {
+				return err
+			}
 			scn := scanner.NewInterval(logger, peripherals)
 			scn.Exporters = exporters
-			return runWithInterval(scn, interval)
+			scn.SetDecryptionKeys(keys)
+			scn.SetCalibrations(calibrations)
+			scn.SetDerivedMetrics(metrics)
+			scn.SetDeduplicator(dedup)
+			scn.SetAggregation(funcs)
+			scn.SetStalenessThreshold(viper.GetDuration("staleness_threshold"))
+			scn.SetRecovery(recoveryConfig())
+			scn.SetCollectAll(collectAll)
+			scn.SetMotionDetector(motion)
+			scn.SetOutlierFilter(outliers)
+			scn.SetQuarantineExporters(quarantine)
+			var schedule scanner.Schedule = scanner.IntervalSchedule{Interval: interval}
+			if cronSpec != "" {
+				expr, err := cron.Parse(cronSpec)
+				if err != nil {
+					return fmt.Errorf("invalid cron expression: %w", err)
+				}
+				schedule = scanner.CronSchedule{
+					Expression:    expr,
+					Duration:      viper.GetDuration("cron_scan_duration"),
+					Jitter:        viper.GetDuration("cron_jitter"),
+					SkipIfRunning: viper.GetBool("cron_skip_if_running"),
+				}
+			}
+			return runWithSchedule(scn, schedule)
 		} else {
 			scn := scanner.NewContinuous(logger, peripherals)
 			scn.Exporters = exporters
+			scn.SetDecryptionKeys(keys)
+			scn.SetCalibrations(calibrations)
+			scn.SetDerivedMetrics(metrics)
+			scn.SetDeduplicator(dedup)
+			scn.SetStalenessThreshold(viper.GetDuration("staleness_threshold"))
+			scn.SetRecovery(recoveryConfig())
+			scn.SetCollectAll(collectAll)
+			scn.SetMotionDetector(motion)
+			scn.SetOutlierFilter(outliers)
+			scn.SetQuarantineExporters(quarantine)
 			return runContinuously(scn)
 		}
 	},
@@ -35,40 +80,103 @@ var daemonCmd = &cobra.Command{
 
 func init() {
 	daemonCmd.Flags().Duration("interval", 60*time.Second, "Wait time between RuuviTag device scans, 0 to scan continuously")
+	daemonCmd.Flags().String("cron", "", "Cron expression with optional seconds field and CRON_TZ= prefix for scheduling scans, overrides interval")
+	daemonCmd.Flags().Duration("cron_scan_duration", scanner.DefaultCronScanDuration, "How long each scan scheduled with cron listens for measurements")
+	daemonCmd.Flags().Duration("cron_jitter", 0, "Delay each scan scheduled with cron by a random duration up to this value")
+	daemonCmd.Flags().Bool("cron_skip_if_running", true, "Skip a scan scheduled with cron if the previous scan is still running")
+	daemonCmd.Flags().Duration("staleness_threshold", 0, "Emit an event when no measurements have been received from a RuuviTag within this time, 0 to disable")
+	daemonCmd.Flags().Duration("recovery.stall_timeout", 10*time.Minute, "Recreate the Bluetooth adapter when no measurements have been received within this time, 0 to disable")
+	daemonCmd.Flags().Duration("recovery.initial_backoff", scanner.DefaultInitialBackoff, "Initial delay between Bluetooth adapter recovery attempts")
+	daemonCmd.Flags().Duration("recovery.max_backoff", scanner.DefaultMaxBackoff, "Maximum delay between Bluetooth adapter recovery attempts")
+	daemonCmd.Flags().String("health.addr", "", "Address for serving scanner health status over HTTP, e.g. :8080")
+	daemonCmd.Flags().Duration("shutdown_grace_period", 30*time.Second, "How long to wait for received measurements to be exported when shutting down")
+	daemonCmd.Flags().Bool("watch_config", true, "Reload RuuviTags and exporters when the config file changes or on SIGHUP")
+	daemonCmd.Flags().StringSlice("aggregate", nil, "Aggregate measurements over each interval with the given functions (mean, min, max, last or all)")
 
 	viper.BindPFlags(daemonCmd.Flags())
 
 	rootCmd.AddCommand(daemonCmd)
 }
 
-func runWithInterval(scn *scanner.Scanner, scanInterval time.Duration) error {
-	if err := scn.Init(device); err != nil {
+func runWithSchedule(scn *scanner.Scanner, schedule scanner.Schedule) error {
+	if err := setupRecording(scn.Engine); err != nil {
 		return err
 	}
-	ctx := context.Background()
-	scn.Scan(ctx, scanInterval)
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-	select {
-	case <-interrupt:
-	case <-scn.Quit:
+	defer closeRecording()
+	if err := scn.Init(devices...); err != nil {
+		return err
 	}
-	scn.Stop()
-	return nil
+	if addr := viper.GetString("health.addr"); addr != "" {
+		srv := serveHealth(addr, scn.Health)
+		defer srv.Close()
+	}
+	stopWatching := func() {}
+	if viper.GetBool("watch_config") {
+		stopWatching = watchConfig(scn.Engine)
+	}
+	ctx := context.Background()
+	running := scn.ScanWithSchedule(ctx, schedule)
+	waitForShutdown(running.Done())
+	stopWatching()
+	return errors.Join(scanError(running), shutdown(scn.Engine))
 }
 
 func runContinuously(scn *scanner.ContinuousScanner) error {
-	if err := scn.Init(device); err != nil {
+	if err := setupRecording(scn.Engine); err != nil {
+		return err
+	}
+	defer closeRecording()
+	if err := scn.Init(devices...); err != nil {
 		return err
 	}
+	if addr := viper.GetString("health.addr"); addr != "" {
+		srv := serveHealth(addr, scn.Health)
+		defer srv.Close()
+	}
+	stopWatching := func() {}
+	if viper.GetBool("watch_config") {
+		stopWatching = watchConfig(scn.Engine)
+	}
 	ctx := context.Background()
-	scn.Scan(ctx)
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
+	running := scn.Scan(ctx)
+	waitForShutdown(running.Done())
+	stopWatching()
+	return errors.Join(scanError(running), shutdown(scn.Engine))
+}
+
+// waitForShutdown waits until the process receives SIGINT or SIGTERM or the scanner quits by itself
+func waitForShutdown(finished <-chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case sig := <-signals:
+		logger.LogAttrs(nil, slog.LevelInfo, "Received signal, shutting down", slog.String("signal", sig.String()))
+	case <-finished:
+	}
+}
+
+// scanError returns the error that ended the scan if the scanner has quit by itself, so that the daemon
+// exits with a failure status
+func scanError(running *scanner.Running) error {
 	select {
-	case <-interrupt:
-	case <-scn.Quit:
+	case <-running.Done():
+	default:
+		return nil
+	}
+	if err := running.Wait(); err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+	return nil
+}
+
+// shutdown stops the scanner and exports the measurements it has already received within the grace period,
+// then closes the exporters and devices
+func shutdown(e *scanner.Engine) error {
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("shutdown_grace_period"))
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down cleanly: %w", err)
 	}
-	scn.Stop()
 	return nil
 }
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
+)
+
+const discoverRefreshInterval = time.Second
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "List nearby RuuviTags",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		duration, err := cmd.Flags().GetDuration("duration")
+		if err != nil {
+			return err
+		}
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		scn := scanner.NewDiscovery(logger)
+		scn.SetDecryptionKeys(keys)
+		if err := setupRecording(scn.Engine); err != nil {
+			return err
+		}
+		defer closeRecording()
+		if err := scn.Init(devices...); err != nil {
+			return err
+		}
+		defer scn.Close()
+		// Keep the table out of the configuration block written to standard output
+		w := cmd.OutOrStdout()
+		if output == "-" {
+			w = cmd.ErrOrStderr()
+		}
+		sightings, err := runDiscovery(scn, duration, w)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			return nil
+		}
+		if output == "-" {
+			return writeRuuviTagsConfig(cmd.OutOrStdout(), sightings)
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := writeRuuviTagsConfig(f, sightings); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+func init() {
+	discoverCmd.Flags().Duration("duration", 30*time.Second, "How long to scan for RuuviTags")
+	discoverCmd.Flags().StringP("output", "o", "", "Write a ruuvitags configuration block of the discovered RuuviTags to the given file, - for standard output")
+	rootCmd.AddCommand(discoverCmd)
+}
+
+// runDiscovery scans for RuuviTags until the duration has passed or the scan is interrupted. The table of
+// sightings is redrawn as it changes if w is a terminal and printed once at the end otherwise.
+func runDiscovery(scn *scanner.DiscoveryScanner, duration time.Duration, w io.Writer) ([]scanner.Sighting, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- scn.Scan(ctx)
+	}()
+	ticker := time.NewTicker(discoverRefreshInterval)
+	defer ticker.Stop()
+	redraw := isTerminal(w)
+	lines := 0
+	for {
+		select {
+		case <-ticker.C:
+			if redraw {
+				lines = printSightings(w, scn.Sightings(), lines)
+			}
+		case err := <-scanErr:
+			sightings := scn.Sightings()
+			printSightings(w, sightings, lines)
+			return sightings, err
+		}
+	}
+}
+
+// printSightings prints the sightings as a table over the previously printed table of the given number
+// of lines and returns the number of lines printed
+func printSightings(w io.Writer, sightings []scanner.Sighting, previousLines int) int {
+	if previousLines > 0 {
+		// Move the cursor to the beginning of the previous table and clear it
+		fmt.Fprintf(w, "\033[%dA\033[J", previousLines)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MAC\tNAME\tFORMAT\tRSSI\tTEMPERATURE\tHUMIDITY\tPRESSURE\tBATTERY\tSIGHTINGS")
+	for _, st := range sightings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%d\n",
+			strings.ToUpper(st.Addr),
+			peripherals[st.Addr],
+			formatName(st.DataFormat),
+			st.RSSI,
+			formatValue(st.Data.Temperature, "%.2f °C"),
+			formatValue(st.Data.Humidity, "%.2f %%"),
+			formatValue(st.Data.Pressure, "%.2f hPa"),
+			formatValue(st.Data.BatteryVoltage, "%.3f V"),
+			st.Count,
+		)
+	}
+	tw.Flush()
+	return len(sightings) + 1
+}
+
+// isTerminal reports whether w is a terminal that can redraw the table with escape codes
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func formatName(format byte) string {
+	if format == 0xE1 {
+		return "E1"
+	}
+	return fmt.Sprintf("%d", format)
+}
+
+func formatValue(v *float64, format string) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *v)
+}
+
+// writeRuuviTagsConfig writes the discovered RuuviTags as a ruuvitags configuration block. Already
+// configured RuuviTags keep their names and other RuuviTags are named after the end of their address.
+func writeRuuviTagsConfig(w io.Writer, sightings []scanner.Sighting) error {
+	var b strings.Builder
+	b.WriteString("ruuvitags:\n")
+	for _, st := range sightings {
+		addr := strings.ToUpper(st.Addr)
+		name, ok := peripherals[st.Addr]
+		if !ok {
+			name = scanner.DefaultTagName(addr)
+		}
+		fmt.Fprintf(&b, "  %q: %q\n", addr, name)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
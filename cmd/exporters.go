@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter/console"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter/http"
+)
+
+// exporterSpec describes how an exporter is created from the configuration
+type exporterSpec struct {
+	name string
+	// enabled is the configuration key that enables the exporter
+	enabled string
+	// settings are the prefixes of the configuration keys the exporter is created from. The exporter is
+	// recreated on reload only if one of these settings has changed.
+	settings []string
+	// usesInputs is set if the exporter is created from the exporterInputs. The exporter is then also
+	// recreated on reload if the inputs have changed.
+	usesInputs bool
+	add        func(exporters *[]exporter.Exporter, in exporterInputs) error
+}
+
+// exporterInputs are the values derived from other parts of the configuration that exporters are created from
+type exporterInputs struct {
+	// rawValues is set if any RuuviTag keeps its raw values alongside the calibrated ones
+	rawValues bool
+}
+
+// inputsOf returns the exporter inputs derived from the RuuviTag calibrations
+func inputsOf(cals map[string]calibration.Calibration) exporterInputs {
+	var in exporterInputs
+	for _, cal := range cals {
+		if cal.KeepRaw {
+			in.rawValues = true
+		}
+	}
+	return in
+}
+
+var exporterSpecs = []exporterSpec{
+	{name: "console", enabled: "console", settings: []string{"console", "console_diagnostics"}, add: addConsoleExporter},
+	{name: "InfluxDB", enabled: "influxdb.enabled", settings: []string{"influxdb"}, add: addInfluxDBExporter},
+	{name: "Google Pub/Sub", enabled: "gcp.pubsub.enabled", settings: []string{"gcp"}, add: addPubSubExporter},
+	{name: "AWS DynamoDB", enabled: "aws.dynamodb.enabled", settings: []string{"aws.access_key_id", "aws.secret_access_key", "aws.session_token", "aws.region", "aws.dynamodb"}, add: addDynamoDBExporter},
+	{name: "AWS SQS", enabled: "aws.sqs.enabled", settings: []string{"aws.access_key_id", "aws.secret_access_key", "aws.session_token", "aws.region", "aws.sqs"}, add: addSQSExporter},
+	{name: "PostgreSQL", enabled: "postgres.enabled", settings: []string{"postgres", "derived_metrics", "aggregate"}, usesInputs: true, add: addPostgresExporter},
+	{name: "HTTP", enabled: "http.enabled", settings: []string{"http"}, add: addHTTPExporter},
+	{name: "MQTT", enabled: "mqtt.enabled", settings: []string{"mqtt"}, add: addMQTTExporter},
+}
+
+// configuredExporters are the exporters created from the configuration, keyed by exporter name
+type configuredExporters map[string]configuredExporter
+
+type configuredExporter struct {
+	settings  string
+	exporters []exporter.Exporter
+}
+
+// buildExporters creates the enabled exporters. Exporters in previous whose settings have not changed are
+// reused. The exporters in previous that are no longer used are returned so that they can be closed.
+// If creating an exporter fails, the exporters created by the call are closed and previous is left intact.
+func buildExporters(previous configuredExporters, in exporterInputs) (configuredExporters, []exporter.Exporter, error) {
+	built := make(configuredExporters)
+	var created []exporter.Exporter
+	for _, spec := range exporterSpecs {
+		if !viper.GetBool(spec.enabled) {
+			continue
+		}
+		settings := settingsOf(spec.settings)
+		if spec.usesInputs {
+			settings += fmt.Sprintf("\ninputs=%+v", in)
+		}
+		if prev, ok := previous[spec.name]; ok && prev.settings == settings {
+			built[spec.name] = prev
+			continue
+		}
+		var exps []exporter.Exporter
+		if err := spec.add(&exps, in); err != nil {
+			closeExporters(created)
+			return nil, nil, fmt.Errorf("failed to create %s exporter: %w", spec.name, err)
+		}
+		created = append(created, exps...)
+		built[spec.name] = configuredExporter{settings: settings, exporters: exps}
+		if previous != nil {
+			logger.LogAttrs(nil, slog.LevelInfo, "Created exporter", slog.String("exporter", spec.name))
+		}
+	}
+	var unused []exporter.Exporter
+	for name, prev := range previous {
+		if cur, ok := built[name]; !ok || cur.settings != prev.settings {
+			unused = append(unused, prev.exporters...)
+		}
+	}
+	return built, unused, nil
+}
+
+// all returns the exporters in the order of exporterSpecs
+func (c configuredExporters) all() []exporter.Exporter {
+	var exps []exporter.Exporter
+	for _, spec := range exporterSpecs {
+		exps = append(exps, c[spec.name].exporters...)
+	}
+	return exps
+}
+
+// settingsOf returns a canonical representation of the configuration values under the given key prefixes
+func settingsOf(prefixes []string) string {
+	var values []string
+	for _, key := range viper.AllKeys() {
+		for _, prefix := range prefixes {
+			if key == prefix || strings.HasPrefix(key, prefix+".") {
+				values = append(values, fmt.Sprintf("%s=%v", key, viper.Get(key)))
+				break
+			}
+		}
+	}
+	sort.Strings(values)
+	return strings.Join(values, "\n")
+}
+
+func closeExporters(exps []exporter.Exporter) {
+	for _, exp := range exps {
+		if err := exp.Close(); err != nil {
+			logger.LogAttrs(nil, slog.LevelError, "Failed to close exporter", slog.String("exporter", exp.Name()), slog.Any("error", err))
+		}
+	}
+}
+
+func addConsoleExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
+	*exporters = append(*exporters, withDiagnostics(console.Exporter{}, "console_diagnostics"))
+	return nil
+}
+
+func addHTTPExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
+	addr := viper.GetString("http.addr")
+	token := viper.GetString("http.token")
+	exp, err := http.New(addr, token, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	*exporters = append(*exporters, withDiagnostics(exp, "http.diagnostics"))
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/gateway"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
+)
+
+var gatewayReceiverCmd = &cobra.Command{
+	Use:   "gateway-receiver",
+	Short: "Receive measurements from Ruuvi Gateways over HTTP",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Starting ruuvitag-gollector")
+		scn := scanner.NewContinuous(logger, peripherals)
+		scn.Exporters = exporters
+		scn.SetDecryptionKeys(keys)
+		scn.SetCalibrations(calibrations)
+		scn.SetDerivedMetrics(metrics)
+		scn.SetDeduplicator(dedup)
+		scn.SetStalenessThreshold(viper.GetDuration("staleness_threshold"))
+		scn.SetCollectAll(collectAll)
+		scn.SetMotionDetector(motion)
+		scn.SetOutlierFilter(outliers)
+		scn.SetQuarantineExporters(quarantine)
+		var adapters []scanner.Adapter
+		if addr := viper.GetString("gateway.addr"); addr != "" {
+			receiver := gateway.NewReceiver(logger, viper.GetString("gateway.token"))
+			srv := &http.Server{
+				Addr:              addr,
+				Handler:           receiver,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.LogAttrs(nil, slog.LevelError, "Gateway receiver failed", slog.Any("error", err))
+					scn.Stop()
+				}
+			}()
+			defer srv.Close()
+			logger.LogAttrs(nil, slog.LevelInfo, "Receiving Ruuvi Gateway HTTP payloads", slog.String("addr", addr))
+			adapters = append(adapters, scanner.Adapter{Name: "http", BLE: receiver})
+		}
+		if viper.GetString("gateway.mqtt.addr") != "" {
+			sub, err := newGatewaySubscriber()
+			if err != nil {
+				return fmt.Errorf("failed to subscribe to Ruuvi Gateway MQTT topics: %w", err)
+			}
+			defer sub.Close()
+			adapters = append(adapters, scanner.Adapter{Name: "mqtt", BLE: sub})
+		}
+		if len(adapters) == 0 {
+			return errors.New("gateway.addr or gateway.mqtt.addr must be specified")
+		}
+		scn.SetAdapters(adapters...)
+		return runContinuously(scn)
+	},
+	PostRun: func(cmd *cobra.Command, args []string) {
+		logger.Info("Stopping ruuvitag-gollector")
+	},
+}
+
+func init() {
+	gatewayReceiverCmd.Flags().String("gateway.addr", ":8080", "Address to listen on for Ruuvi Gateway HTTP payloads, empty to disable")
+	gatewayReceiverCmd.Flags().String("gateway.token", "", "Bearer token Ruuvi Gateways must send, empty to accept all requests")
+	gatewayReceiverCmd.Flags().String("gateway.mqtt.addr", "", "MQTT broker address with protocol (tcp or ssl), host and port to receive Ruuvi Gateway messages from")
+	gatewayReceiverCmd.Flags().String("gateway.mqtt.client_id", "ruuvitag-gollector-receiver", "MQTT client id")
+	gatewayReceiverCmd.Flags().String("gateway.mqtt.username", "", "MQTT username")
+	gatewayReceiverCmd.Flags().String("gateway.mqtt.password", "", "MQTT password")
+	gatewayReceiverCmd.Flags().String("gateway.mqtt.ca_file", "", "Path to a CA file, if TLS used")
+	gatewayReceiverCmd.Flags().StringSlice("gateway.mqtt.topics", []string{gateway.DefaultTopic}, "MQTT topic filters Ruuvi Gateways publish to")
+
+	viper.BindPFlags(gatewayReceiverCmd.Flags())
+
+	rootCmd.AddCommand(gatewayReceiverCmd)
+}
+
+// gatewaySubscriber is a source of advertisements relayed by Ruuvi Gateways over MQTT
+type gatewaySubscriber interface {
+	scanner.BLEScanner
+	io.Closer
+}
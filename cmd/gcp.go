@@ -20,9 +20,10 @@ func init() {
 	rootCmd.PersistentFlags().String("gcp.project", "", "Google Cloud Platform project")
 	rootCmd.PersistentFlags().Bool("gcp.pubsub.enabled", false, "Send measurements to Google Pub/Sub")
 	rootCmd.PersistentFlags().String("gcp.pubsub.topic", "", "Google Pub/Sub topic to use")
+	rootCmd.PersistentFlags().Bool("gcp.pubsub.diagnostics", false, "Include RSSI and raw advertisement data in Google Pub/Sub messages")
 }
 
-func addPubSubExporter(exporters *[]exporter.Exporter) error {
+func addPubSubExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	ctx := context.Background()
 	project := viper.GetString("gcp.project")
 	if project == "" {
@@ -36,6 +37,6 @@ func addPubSubExporter(exporters *[]exporter.Exporter) error {
 	if err != nil {
 		return err
 	}
-	*exporters = append(*exporters, ps)
+	*exporters = append(*exporters, withDiagnostics(ps, "gcp.pubsub.diagnostics"))
 	return nil
 }
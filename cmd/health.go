@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/spf13/viper"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
+)
+
+// serveHealth serves the scanner health status as JSON on the given address. The response status is
+// 200 OK when the scanner is healthy and 503 Service Unavailable otherwise.
+func serveHealth(addr string, health func() scanner.Health) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		h := health()
+		w.Header().Set("Content-Type", "application/json")
+		if h.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(h)
+	})
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.LogAttrs(nil, slog.LevelError, "Health endpoint failed", slog.Any("error", err))
+		}
+	}()
+	logger.LogAttrs(nil, slog.LevelInfo, "Serving health status", slog.String("addr", addr))
+	return srv
+}
+
+func recoveryConfig() scanner.RecoveryConfig {
+	return scanner.RecoveryConfig{
+		StallTimeout:   viper.GetDuration("recovery.stall_timeout"),
+		InitialBackoff: viper.GetDuration("recovery.initial_backoff"),
+		MaxBackoff:     viper.GetDuration("recovery.max_backoff"),
+	}
+}
@@ -22,9 +22,10 @@ func init() {
 	rootCmd.PersistentFlags().String("influxdb.token", "", "InfluxDB token")
 	rootCmd.PersistentFlags().String("influxdb.username", "", "InfluxDB username (1.x)")
 	rootCmd.PersistentFlags().String("influxdb.password", "", "InfluxDB password (1.x)")
+	rootCmd.PersistentFlags().Bool("influxdb.diagnostics", false, "Store RSSI and raw advertisement data to InfluxDB")
 }
 
-func addInfluxDBExporter(exporters *[]exporter.Exporter) error {
+func addInfluxDBExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	addr := viper.GetString("influxdb.addr")
 	if addr == "" {
 		return fmt.Errorf("InfluxDB address must be specified")
@@ -41,6 +42,6 @@ func addInfluxDBExporter(exporters *[]exporter.Exporter) error {
 	}
 	logger.LogAttrs(nil, slog.LevelInfo, "Connecting to InfluxDB", slog.String("addr", cfg.Addr), slog.String("org", cfg.Org), slog.String("bucket", cfg.Bucket), slog.String("database", cfg.Database), slog.String("measurement", cfg.Measurement))
 	influx := influxdb.New(cfg)
-	*exporters = append(*exporters, influx)
+	*exporters = append(*exporters, withDiagnostics(influx, "influxdb.diagnostics"))
 	return nil
 }
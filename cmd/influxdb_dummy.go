@@ -4,6 +4,6 @@ package cmd
 
 import "github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 
-func addInfluxDBExporter(exporters *[]exporter.Exporter) error {
+func addInfluxDBExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	return ErrNotEnabled
 }
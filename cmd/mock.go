@@ -45,14 +45,14 @@ func generateMockData(addr, name string, ts time.Time) sensor.Data {
 	return sensor.Data{
 		Addr:            addr,
 		Name:            name,
-		Temperature:     21.5,
-		Humidity:        60,
-		Pressure:        1002,
-		BatteryVoltage:  2.755,
-		AccelerationX:   0,
-		AccelerationY:   0,
-		AccelerationZ:   0,
-		MovementCounter: 0,
+		Temperature:     sensor.Float64(21.5),
+		Humidity:        sensor.Float64(60),
+		Pressure:        sensor.Float64(1002),
+		BatteryVoltage:  sensor.Float64(2.755),
+		AccelerationX:   sensor.Int(0),
+		AccelerationY:   sensor.Int(0),
+		AccelerationZ:   sensor.Int(0),
+		MovementCounter: sensor.Int(0),
 		Timestamp:       ts,
 	}
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter/mqtt"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/gateway"
 )
 
 func init() {
@@ -21,9 +22,25 @@ func init() {
 	rootCmd.PersistentFlags().String("mqtt.ca_file", "", "Path to a CA file, if TLS used")
 	rootCmd.PersistentFlags().Bool("mqtt.auto_reconnect", false, "Enable auto reconnection if connection is lost")
 	rootCmd.PersistentFlags().Int("mqtt.reconnect_interval", 60, "Sets the maximum time in seconds that will be waited between reconnection attempts")
+	rootCmd.PersistentFlags().Bool("mqtt.diagnostics", false, "Include RSSI and raw advertisement data in MQTT messages")
 }
 
-func addMQTTExporter(exporters *[]exporter.Exporter) error {
+func newGatewaySubscriber() (gatewaySubscriber, error) {
+	sub, err := gateway.NewSubscriber(logger, gateway.SubscriberConfig{
+		Addr:     viper.GetString("gateway.mqtt.addr"),
+		ClientId: viper.GetString("gateway.mqtt.client_id"),
+		Username: viper.GetString("gateway.mqtt.username"),
+		Password: viper.GetString("gateway.mqtt.password"),
+		CaFile:   viper.GetString("gateway.mqtt.ca_file"),
+		Topics:   viper.GetStringSlice("gateway.mqtt.topics"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func addMQTTExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	addr := viper.GetString("mqtt.addr")
 	if addr == "" {
 		return fmt.Errorf("MQTT broker address must be specified")
@@ -40,6 +57,6 @@ func addMQTTExporter(exporters *[]exporter.Exporter) error {
 	if err != nil {
 		return err
 	}
-	*exporters = append(*exporters, exporter)
+	*exporters = append(*exporters, withDiagnostics(exporter, "mqtt.diagnostics"))
 	return nil
 }
@@ -4,6 +4,10 @@ package cmd
 
 import "github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 
-func addMQTTExporter(exporters *[]exporter.Exporter) error {
+func addMQTTExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	return ErrNotEnabled
 }
+
+func newGatewaySubscriber() (gatewaySubscriber, error) {
+	return nil, ErrNotEnabled
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter/http"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/outlier"
+)
+
+// loadOutlierFilter creates the outlier filter from the outlier.rules setting and the outlier rules of the
+// RuuviTags. Returns nil if no rules have been configured.
+func loadOutlierFilter() (*outlier.Filter, error) {
+	defaults, err := parseOutlierRules(viper.Get("outlier.rules"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid outlier rules: %w", err)
+	}
+	ruuviTags, err := parseRuuviTags(viper.GetStringMap("ruuvitags"))
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string][]outlier.Rule)
+	for addr, tag := range ruuviTags {
+		if len(tag.Outlier) > 0 {
+			tags[addr] = tag.Outlier
+		}
+	}
+	if len(defaults) == 0 && len(tags) == 0 {
+		return nil, nil
+	}
+	return outlier.New(defaults, tags)
+}
+
+// parseOutlierRules parses a list of outlier rules:
+//
+//   - field: temperature
+//     method: hampel
+//     window: 7
+//     threshold: 3
+//     tolerance: 0.5
+//   - field: humidity
+//     method: rate
+//     threshold: 10
+func parseOutlierRules(v interface{}) ([]outlier.Rule, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("outlier rules must be a list")
+	}
+	var rules []outlier.Rule
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("outlier rule must be a map")
+		}
+		var (
+			r   outlier.Rule
+			err error
+		)
+		if r.Field, ok = m["field"].(string); !ok {
+			return nil, fmt.Errorf("field must be specified for outlier rule")
+		}
+		method, ok := m["method"].(string)
+		if !ok {
+			return nil, fmt.Errorf("method must be specified for outlier rule of %s", r.Field)
+		}
+		r.Method = outlier.Method(method)
+		window, err := toFloat(m["window"])
+		if err != nil || window != math.Trunc(window) {
+			return nil, fmt.Errorf("window of outlier rule of %s must be an integer", r.Field)
+		}
+		r.Window = int(window)
+		if r.Threshold, err = toFloat(m["threshold"]); err != nil {
+			return nil, fmt.Errorf("threshold of outlier rule of %s: %w", r.Field, err)
+		}
+		if r.Tolerance, err = toFloat(m["tolerance"]); err != nil {
+			return nil, fmt.Errorf("tolerance of outlier rule of %s: %w", r.Field, err)
+		}
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// quarantineExporters creates the exporters that receive the measurements rejected by the outlier filter.
+// Diagnostics are always included since they help to tell faulty sensors from bad reception.
+func quarantineExporters() ([]exporter.Exporter, error) {
+	addr := viper.GetString("outlier.quarantine.addr")
+	if addr == "" {
+		return nil, nil
+	}
+	exp, err := http.New(addr, viper.GetString("outlier.quarantine.token"), 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quarantine exporter: %w", err)
+	}
+	return []exporter.Exporter{exp}, nil
+}
@@ -15,13 +15,27 @@ func init() {
 	rootCmd.PersistentFlags().Bool("postgres.enabled", false, "Store measurements to PostgreSQL")
 	rootCmd.PersistentFlags().String("postgres.conn", "", "PostgreSQL connection string")
 	rootCmd.PersistentFlags().String("postgres.table", "", "PostgreSQL table")
+	rootCmd.PersistentFlags().Bool("postgres.diagnostics", false, "Store RSSI and raw advertisement data to PostgreSQL")
+	rootCmd.PersistentFlags().Bool("postgres.air_quality", false, "Store Ruuvi Air particulate matter, CO2, VOC, NOx, luminosity and sound measurements to PostgreSQL")
 }
 
-func addPostgresExporter(exporters *[]exporter.Exporter) error {
+// postgresConfig returns the PostgreSQL settings. Optional column groups are enabled only when configured
+// so that tables created by earlier versions keep working without the new columns.
+func postgresConfig(rawValues bool) postgres.Config {
+	return postgres.Config{
+		ConnString:     viper.GetString("postgres.conn"),
+		Table:          viper.GetString("postgres.table"),
+		AirQuality:     viper.GetBool("postgres.air_quality"),
+		DerivedMetrics: len(viper.GetStringSlice("derived_metrics")) > 0,
+		RawValues:      rawValues,
+		Aggregates:     len(viper.GetStringSlice("aggregate")) > 0,
+		Diagnostics:    viper.GetBool("postgres.diagnostics"),
+	}
+}
+
+func addPostgresExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	ctx := context.Background()
-	connStr := viper.GetString("postgres.conn")
-	table := viper.GetString("postgres.table")
-	exp, err := postgres.New(ctx, connStr, table)
+	exp, err := postgres.New(ctx, postgresConfig(in.rawValues))
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,6 @@ package cmd
 
 import "github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 
-func addPostgresExporter(exporters *[]exporter.Exporter) error {
+func addPostgresExporter(exporters *[]exporter.Exporter, in exporterInputs) error {
 	return ErrNotEnabled
 }
@@ -9,17 +9,16 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	pexp "github.com/niktheblak/ruuvitag-gollector/pkg/exporter/postgres"
 )
 
 var postgresSchemaCmd = &cobra.Command{
 	Use:   "postgres-schema",
-	Short: "Create PostgreSQL schema",
+	Short: "Create PostgreSQL schema or add the columns of enabled features to an existing table",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		conn := viper.GetString("postgres.conn")
-		table := viper.GetString("postgres.table")
+		cfg := postgresConfig(inputsOf(calibrations).rawValues)
+		conn, table := cfg.ConnString, cfg.Table
 		logger.LogAttrs(nil, slog.LevelInfo, "Creating schema", slog.String("conn", conn), slog.String("table", table))
 		schema := fmt.Sprintf(pexp.SchemaTmpl, table)
 		db, err := sql.Open("postgres", conn)
@@ -31,10 +30,15 @@ var postgresSchemaCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		_, err = db.ExecContext(cmd.Context(), fmt.Sprintf("CREATE INDEX idx_name ON %s(name)", table))
+		_, err = db.ExecContext(cmd.Context(), fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_name ON %s(name)", table))
 		if err != nil {
 			return err
 		}
+		for _, stmt := range pexp.Migrations(cfg) {
+			if _, err := db.ExecContext(cmd.Context(), stmt); err != nil {
+				return err
+			}
+		}
 		return nil
 	},
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
+)
+
+var recording *os.File
+
+func init() {
+	rootCmd.PersistentFlags().String("record", "", "Append every received advertisement to this JSON Lines file")
+	rootCmd.PersistentFlags().String("replay", "", "Replay advertisements from a recording instead of scanning with Bluetooth devices")
+	rootCmd.PersistentFlags().Float64("replay_speed", 1, "Replay speed relative to the recorded speed, 0 to replay as fast as possible")
+}
+
+// setupRecording configures the scanner to record advertisements to a file or to replay them from a file
+func setupRecording(e *scanner.Engine) error {
+	if path := viper.GetString("replay"); path != "" {
+		r, err := scanner.OpenReplay(path, viper.GetFloat64("replay_speed"))
+		if err != nil {
+			return fmt.Errorf("failed to open recording: %w", err)
+		}
+		logger.LogAttrs(nil, slog.LevelInfo, "Replaying advertisements", slog.String("file", path), slog.Int("records", r.Remaining()))
+		e.SetBLEScanner(r)
+	}
+	if path := viper.GetString("record"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create recording: %w", err)
+		}
+		logger.LogAttrs(nil, slog.LevelInfo, "Recording advertisements", slog.String("file", path))
+		recording = f
+		e.SetRecorder(scanner.NewRecorder(f))
+	}
+	return nil
+}
+
+func closeRecording() {
+	if recording == nil {
+		return
+	}
+	if err := recording.Close(); err != nil {
+		logger.LogAttrs(nil, slog.LevelError, "Failed to close recording", slog.Any("error", err))
+	}
+	recording = nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
+)
+
+// reloadDelay is how long to wait for the config file to settle after a change before reloading it.
+// Editors often write a file in several steps.
+const reloadDelay = 500 * time.Millisecond
+
+// watchConfig reloads the configuration of the running scanner when the config file changes or the process
+// receives SIGHUP. Watching stops when the returned function is called.
+func watchConfig(scn *scanner.Engine) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	var (
+		changes <-chan fsnotify.Event
+		errs    <-chan error
+	)
+	file := viper.ConfigFileUsed()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.LogAttrs(nil, slog.LevelError, "Failed to watch config file", slog.Any("error", err))
+	} else if file != "" {
+		// The directory is watched since editors and configuration management often replace the file
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			logger.LogAttrs(nil, slog.LevelError, "Failed to watch config file", slog.String("file", file), slog.Any("error", err))
+		} else {
+			logger.LogAttrs(nil, slog.LevelInfo, "Watching config file for changes", slog.String("file", file))
+			changes = watcher.Events
+			errs = watcher.Errors
+		}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-hup:
+				logger.Info("Received SIGHUP, reloading configuration")
+				reload(scn)
+			case ev := <-changes:
+				if filepath.Clean(ev.Name) == filepath.Clean(file) && ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					settled = time.After(reloadDelay)
+				}
+			case <-settled:
+				settled = nil
+				logger.LogAttrs(nil, slog.LevelInfo, "Config file changed, reloading configuration", slog.String("file", file))
+				reload(scn)
+			case err := <-errs:
+				logger.LogAttrs(nil, slog.LevelError, "Error while watching config file", slog.Any("error", err))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(done)
+		<-finished
+		if watcher != nil {
+			watcher.Close()
+		}
+	}
+}
+
+func reload(scn *scanner.Engine) {
+	if err := reloadConfig(scn); err != nil {
+		logger.LogAttrs(nil, slog.LevelError, "Failed to reload configuration, keeping the previous configuration", slog.Any("error", err))
+	}
+}
+
+// reloadConfig re-reads the config file and applies the peripherals, motion settings, outlier rules and
+// exporters to the running scanner. Only the exporters whose settings have changed are recreated and the
+// replaced exporters are closed once the exports in progress have finished. The motion detector and the
+// outlier filter start over from the next measurements. Other settings take effect after a restart.
+func reloadConfig(scn *scanner.Engine) error {
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	p, k, c, err := loadRuuviTags()
+	if err != nil {
+		return err
+	}
+	if len(p) == 0 && collectAll == nil {
+		return errors.New("at least one RuuviTag address must be specified")
+	}
+	m, err := loadMotionDetector()
+	if err != nil {
+		return err
+	}
+	o, err := loadOutlierFilter()
+	if err != nil {
+		return err
+	}
+	built, unused, err := buildExporters(configured, inputsOf(c))
+	if err != nil {
+		return err
+	}
+	scn.SetPeripherals(p, k, c)
+	scn.SetMotionDetector(m)
+	scn.SetOutlierFilter(o)
+	configured = built
+	exporters = built.all()
+	scn.SetExporters(exporters)
+	closeExporters(unused)
+	logger.LogAttrs(nil, slog.LevelInfo, "Reloaded configuration", slog.Int("exporters", len(exporters)), slog.Int("closed_exporters", len(unused)))
+	return nil
+}
@@ -2,28 +2,36 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
 	"log"
 	"log/slog"
 	"os"
-	"time"
 
-	"github.com/go-ble/ble"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
-	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter/console"
-	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter/http"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/outlier"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/psychrometrics"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
 )
 
 var ErrNotEnabled = errors.New("this exporter is not included in the build")
 
 var (
-	logger      *slog.Logger
-	peripherals map[string]string
-	exporters   []exporter.Exporter
-	device      string
+	logger       *slog.Logger
+	peripherals  map[string]string
+	keys         map[string][]byte
+	calibrations map[string]calibration.Calibration
+	metrics      []psychrometrics.Metric
+	dedup        *scanner.Deduplicator
+	exporters    []exporter.Exporter
+	devices      []string
+	collectAll   *scanner.CollectAll
+	motion       *scanner.MotionDetector
+	outliers     *outlier.Filter
+	quarantine   []exporter.Exporter
+	configured   configuredExporters
 )
 
 var rootCmd = &cobra.Command{
@@ -46,13 +54,29 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringToString("ruuvitags", nil, "RuuviTag addresses and names to use")
-	rootCmd.PersistentFlags().String("device", "default", "HCL device to use")
+	rootCmd.PersistentFlags().StringSlice("device", []string{"default"}, "HCI devices to scan with, multiple devices are scanned concurrently")
 	rootCmd.PersistentFlags().BoolP("console", "c", false, "Print measurements to console")
+	rootCmd.PersistentFlags().Bool("console_diagnostics", false, "Include RSSI and raw advertisement data in console output")
 	rootCmd.PersistentFlags().String("loglevel", "info", "Log level")
+	rootCmd.PersistentFlags().Bool("dedup.enabled", false, "Drop repeated broadcasts of the same measurement")
+	rootCmd.PersistentFlags().Duration("dedup.window", scanner.DefaultDedupWindow, "Time window for detecting repeated broadcasts of measurements")
+	rootCmd.PersistentFlags().Bool("collect_all.enabled", false, "Collect measurements from all RuuviTags in range, not only the specified ones")
+	rootCmd.PersistentFlags().String("collect_all.name_template", scanner.DefaultNameTemplate, "Template for naming RuuviTags that have not been specified, with fields .Addr, .MAC and .Suffix")
+	rootCmd.PersistentFlags().StringSlice("collect_all.allow", nil, "Only collect unspecified RuuviTags whose MAC address starts with one of these prefixes")
+	rootCmd.PersistentFlags().StringSlice("collect_all.deny", nil, "Ignore unspecified RuuviTags whose MAC address starts with one of these prefixes")
+	rootCmd.PersistentFlags().Duration("collect_all.quiet_period", scanner.DefaultQuietPeriod, "Finish collecting once no new RuuviTags have been seen within this time")
+	rootCmd.PersistentFlags().Bool("motion.enabled", false, "Export measurements immediately and emit motion events when RuuviTags move")
+	rootCmd.PersistentFlags().Int("motion.movement_threshold", scanner.DefaultMovementThreshold, "Movement counter increments that count as motion, 0 to ignore the movement counter")
+	rootCmd.PersistentFlags().Float64("motion.acceleration_threshold", 0, "Change in acceleration in mG that counts as motion, 0 to ignore acceleration")
+	rootCmd.PersistentFlags().Duration("motion.debounce", scanner.DefaultMotionDebounce, "Minimum time between motion events of a RuuviTag")
+	rootCmd.PersistentFlags().String("outlier.quarantine.addr", "", "HTTP endpoint that receives the measurements rejected by the outlier filter")
+	rootCmd.PersistentFlags().String("outlier.quarantine.token", "", "Authorization token of the quarantine HTTP endpoint")
+	rootCmd.PersistentFlags().StringSlice("derived_metrics", nil, "Derived metrics to calculate (absolute_humidity, vapor_pressure_deficit, humidity_ratio, wet_bulb, frost_point, heat_index, air_density or all)")
 
 	rootCmd.PersistentFlags().Bool("http.enabled", false, "Send measurements as JSON to a HTTP endpoint")
 	rootCmd.PersistentFlags().String("http.addr", "", "HTTP receiver address")
 	rootCmd.PersistentFlags().String("http.token", "", "HTTP receiver authorization token")
+	rootCmd.PersistentFlags().Bool("http.diagnostics", false, "Include RSSI and raw advertisement data in HTTP exports")
 
 	if err := viper.BindPFlags(rootCmd.PersistentFlags()); err != nil {
 		panic(err)
@@ -70,7 +94,7 @@ func initConfig() {
 	}
 }
 
-func run(_ *cobra.Command, _ []string) error {
+func run(cmd *cobra.Command, _ []string) error {
 	creds := viper.GetString("gcp.credentials")
 	if creds != "" {
 		if err := os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", creds); err != nil {
@@ -87,58 +111,114 @@ func run(_ *cobra.Command, _ []string) error {
 	}
 	h := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: programLevel})
 	logger = slog.New(h)
-	ruuviTags := viper.GetStringMapString("ruuvitags")
-	if len(ruuviTags) == 0 {
+	var err error
+	if viper.GetBool("collect_all.enabled") {
+		collectAll, err = scanner.NewCollectAll(
+			viper.GetString("collect_all.name_template"),
+			viper.GetStringSlice("collect_all.allow"),
+			viper.GetStringSlice("collect_all.deny"),
+			viper.GetDuration("collect_all.quiet_period"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	peripherals, keys, calibrations, err = loadRuuviTags()
+	if err != nil {
+		return err
+	}
+	if len(peripherals) == 0 && collectAll == nil && cmd != discoverCmd {
 		logger.LogAttrs(nil, slog.LevelError, "At least one RuuviTag address must be specified")
 		os.Exit(1)
 	}
-	logger.LogAttrs(nil, slog.LevelInfo, "RuuviTags", slog.Any("ruuvitags", ruuviTags))
-	peripherals = make(map[string]string)
-	for addr, name := range ruuviTags {
-		peripherals[ble.NewAddr(addr).String()] = name
+	logger.LogAttrs(nil, slog.LevelInfo, "RuuviTags", slog.Any("ruuvitags", peripherals))
+	motion, err = loadMotionDetector()
+	if err != nil {
+		return err
 	}
-	if viper.GetBool("console") {
-		exporters = append(exporters, console.Exporter{})
+	outliers, err = loadOutlierFilter()
+	if err != nil {
+		return err
 	}
-	if viper.GetBool("influxdb.enabled") {
-		if err := addInfluxDBExporter(&exporters); err != nil {
-			return fmt.Errorf("failed to create InfluxDB exporter: %w", err)
+	if outliers != nil {
+		if quarantine, err = quarantineExporters(); err != nil {
+			return err
 		}
 	}
-	if viper.GetBool("gcp.pubsub.enabled") {
-		if err := addPubSubExporter(&exporters); err != nil {
-			return fmt.Errorf("failed to create Google Pub/Sub exporter: %w", err)
-		}
+	metrics, err = psychrometrics.ParseMetrics(viper.GetStringSlice("derived_metrics"))
+	if err != nil {
+		return err
 	}
-	if viper.GetBool("aws.dynamodb.enabled") {
-		if err := addDynamoDBExporter(&exporters); err != nil {
-			return fmt.Errorf("failed to create AWS DynamoDB exporter: %w", err)
-		}
+	if viper.GetBool("dedup.enabled") {
+		dedup = scanner.NewDeduplicator(viper.GetDuration("dedup.window"))
 	}
-	if viper.GetBool("aws.sqs.enabled") {
-		if err := addSQSExporter(&exporters); err != nil {
-			return fmt.Errorf("failed to create AWS SQS exporter: %w", err)
-		}
+	configured, _, err = buildExporters(nil, inputsOf(calibrations))
+	if err != nil {
+		return err
 	}
-	if viper.GetBool("postgres.enabled") {
-		if err := addPostgresExporter(&exporters); err != nil {
-			return fmt.Errorf("failed to create PostgreSQL exporter: %w", err)
-		}
+	exporters = configured.all()
+	devices = viper.GetStringSlice("device")
+	if len(devices) == 0 {
+		devices = []string{"default"}
 	}
-	if viper.GetBool("http.enabled") {
-		addr := viper.GetString("http.addr")
-		token := viper.GetString("http.token")
-		exp, err := http.New(addr, token, 10*time.Second)
-		if err != nil {
-			return fmt.Errorf("failed to create HTTP exporter: %w", err)
+	return nil
+}
+
+// loadRuuviTags reads the peripherals and their decryption keys and calibrations from the configuration
+func loadRuuviTags() (map[string]string, map[string][]byte, map[string]calibration.Calibration, error) {
+	ruuviTags, err := parseRuuviTags(viper.GetStringMap("ruuvitags"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	peripherals := make(map[string]string)
+	keys := make(map[string][]byte)
+	calibrations := make(map[string]calibration.Calibration)
+	for addr, tag := range ruuviTags {
+		peripherals[addr] = tag.Name
+		if tag.Key != nil {
+			keys[addr] = tag.Key
+		}
+		if !tag.Calibration.IsIdentity() {
+			calibrations[addr] = tag.Calibration
 		}
-		exporters = append(exporters, exp)
 	}
-	if viper.GetBool("mqtt.enabled") {
-		if err := addMQTTExporter(&exporters); err != nil {
-			return fmt.Errorf("failed to create MQTT exporter: %w", err)
+	return peripherals, keys, calibrations, nil
+}
+
+// loadMotionDetector creates the motion detector from the configuration. Motion is detected for every
+// RuuviTag if motion.enabled is set and otherwise only for the RuuviTags that have motion settings.
+// Returns nil if motion is not detected for any RuuviTag.
+func loadMotionDetector() (*scanner.MotionDetector, error) {
+	ruuviTags, err := parseRuuviTags(viper.GetStringMap("ruuvitags"))
+	if err != nil {
+		return nil, err
+	}
+	defaults := scanner.MotionConfig{
+		MovementThreshold:     viper.GetInt("motion.movement_threshold"),
+		AccelerationThreshold: viper.GetFloat64("motion.acceleration_threshold"),
+		Debounce:              viper.GetDuration("motion.debounce"),
+	}
+	tags := make(map[string]scanner.MotionConfig)
+	for addr, tag := range ruuviTags {
+		if tag.Motion != nil {
+			tags[addr] = tag.Motion.apply(defaults)
 		}
 	}
-	device = viper.GetString("device")
-	return nil
+	var all *scanner.MotionConfig
+	if viper.GetBool("motion.enabled") {
+		all = &defaults
+	}
+	if all == nil && len(tags) == 0 {
+		return nil, nil
+	}
+	return scanner.NewMotionDetector(all, tags), nil
+}
+
+// withDiagnostics strips advertisement diagnostics from the data sent to the exporter
+// unless diagnostics have been enabled with the given configuration key
+func withDiagnostics(exp exporter.Exporter, key string) exporter.Exporter {
+	if viper.GetBool(key) {
+		return exp
+	}
+	return exporter.WithoutDiagnostics(exp)
 }
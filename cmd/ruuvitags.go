@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-ble/ble"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/outlier"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/scanner"
+)
+
+// ruuviTag contains the configuration of a single RuuviTag
+type ruuviTag struct {
+	Name        string
+	Key         []byte
+	Calibration calibration.Calibration
+	Motion      *motionSettings
+	Outlier     []outlier.Rule
+}
+
+// motionSettings are the motion detection settings of a single RuuviTag. Unset settings fall back to the
+// global motion settings.
+type motionSettings struct {
+	MovementThreshold     *int
+	AccelerationThreshold *float64
+	Debounce              *time.Duration
+}
+
+// parseRuuviTags parses the ruuvitags configuration. Each tag is configured either with just a name:
+//
+//	"CC:CA:7E:52:CC:34": Backyard
+//
+// or with a map of settings:
+//
+//	"CC:CA:7E:52:CC:34":
+//	  name: Backyard
+//	  key: 000102030405060708090A0B0C0D0E0F
+//	  calibration:
+//	    temperature:
+//	      offset: -0.8
+//	    humidity:
+//	      offset: 4.0
+//	      slope: 1.02
+//	    keep_raw: true
+//	  motion:
+//	    movement_threshold: 2
+//	    acceleration_threshold: 300
+//	    debounce: 1m
+//	  outlier:
+//	    - field: temperature
+//	      method: rate
+//	      threshold: 20
+func parseRuuviTags(cfg map[string]interface{}) (map[string]ruuviTag, error) {
+	tags := make(map[string]ruuviTag)
+	for addr, v := range cfg {
+		var tag ruuviTag
+		switch v := v.(type) {
+		case string:
+			tag.Name = v
+		case map[string]interface{}:
+			name, ok := v["name"].(string)
+			if !ok {
+				return nil, fmt.Errorf("name must be specified for RuuviTag %s", addr)
+			}
+			tag.Name = name
+			if k, ok := v["key"]; ok {
+				key, err := parseKey(k)
+				if err != nil {
+					return nil, fmt.Errorf("invalid key for RuuviTag %s: %w", addr, err)
+				}
+				tag.Key = key
+			}
+			if c, ok := v["calibration"]; ok {
+				cal, err := parseCalibration(c)
+				if err != nil {
+					return nil, fmt.Errorf("invalid calibration for RuuviTag %s: %w", addr, err)
+				}
+				tag.Calibration = cal
+			}
+			if m, ok := v["motion"]; ok {
+				motion, err := parseMotion(m)
+				if err != nil {
+					return nil, fmt.Errorf("invalid motion settings for RuuviTag %s: %w", addr, err)
+				}
+				tag.Motion = motion
+			}
+			if o, ok := v["outlier"]; ok {
+				rules, err := parseOutlierRules(o)
+				if err != nil {
+					return nil, fmt.Errorf("invalid outlier rules for RuuviTag %s: %w", addr, err)
+				}
+				tag.Outlier = rules
+			}
+		default:
+			return nil, fmt.Errorf("invalid configuration for RuuviTag %s", addr)
+		}
+		tags[ble.NewAddr(addr).String()] = tag
+	}
+	return tags, nil
+}
+
+func parseKey(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("key must be a hex string")
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("key must be 128 bits, was %d bits", len(key)*8)
+	}
+	return key, nil
+}
+
+func parseCalibration(v interface{}) (cal calibration.Calibration, err error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return cal, fmt.Errorf("calibration must be a map")
+	}
+	if cal.Temperature, err = parseLinear(m["temperature"]); err != nil {
+		return cal, fmt.Errorf("temperature: %w", err)
+	}
+	if cal.Humidity, err = parseLinear(m["humidity"]); err != nil {
+		return cal, fmt.Errorf("humidity: %w", err)
+	}
+	if cal.Pressure, err = parseLinear(m["pressure"]); err != nil {
+		return cal, fmt.Errorf("pressure: %w", err)
+	}
+	if keepRaw, ok := m["keep_raw"]; ok {
+		if cal.KeepRaw, ok = keepRaw.(bool); !ok {
+			return cal, fmt.Errorf("keep_raw must be a boolean")
+		}
+	}
+	return cal, nil
+}
+
+func parseMotion(v interface{}) (*motionSettings, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("motion must be a map")
+	}
+	settings := new(motionSettings)
+	if v, ok := m["movement_threshold"]; ok {
+		n, err := toFloat(v)
+		if err != nil || n < 0 || n != math.Trunc(n) {
+			return nil, fmt.Errorf("movement_threshold must be a non-negative integer")
+		}
+		threshold := int(n)
+		settings.MovementThreshold = &threshold
+	}
+	if v, ok := m["acceleration_threshold"]; ok {
+		threshold, err := toFloat(v)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("acceleration_threshold must be a non-negative number")
+		}
+		settings.AccelerationThreshold = &threshold
+	}
+	if v, ok := m["debounce"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("debounce must be a duration")
+		}
+		debounce, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("debounce: %w", err)
+		}
+		settings.Debounce = &debounce
+	}
+	return settings, nil
+}
+
+// apply returns the global motion settings overridden with the settings of the RuuviTag
+func (s *motionSettings) apply(cfg scanner.MotionConfig) scanner.MotionConfig {
+	if s.MovementThreshold != nil {
+		cfg.MovementThreshold = *s.MovementThreshold
+	}
+	if s.AccelerationThreshold != nil {
+		cfg.AccelerationThreshold = *s.AccelerationThreshold
+	}
+	if s.Debounce != nil {
+		cfg.Debounce = *s.Debounce
+	}
+	return cfg
+}
+
+func parseLinear(v interface{}) (l calibration.Linear, err error) {
+	if v == nil {
+		return l, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return l, fmt.Errorf("correction must be a map")
+	}
+	if l.Offset, err = toFloat(m["offset"]); err != nil {
+		return l, fmt.Errorf("offset: %w", err)
+	}
+	if l.Slope, err = toFloat(m["slope"]); err != nil {
+		return l, fmt.Errorf("slope: %w", err)
+	}
+	return l, nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+}
@@ -0,0 +1,209 @@
+package aggregate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// Func is an aggregation function applied to each measured quantity of the measurements of a peripheral
+type Func string
+
+const (
+	Mean Func = "mean"
+	Min  Func = "min"
+	Max  Func = "max"
+	Last Func = "last"
+)
+
+// AllFuncs contains every supported aggregation function
+var AllFuncs = []Func{Mean, Min, Max, Last}
+
+// ParseFuncs parses aggregation function names. The name "all" selects every function.
+func ParseFuncs(names []string) ([]Func, error) {
+	var funcs []Func
+	for _, name := range names {
+		if name == "all" {
+			return AllFuncs, nil
+		}
+		f := Func(name)
+		if !isSupported(f) {
+			return nil, fmt.Errorf("unknown aggregation function: %s", name)
+		}
+		funcs = append(funcs, f)
+	}
+	return funcs, nil
+}
+
+// Aggregator collects measurements of peripherals over a time window.
+// Aggregator is safe for concurrent use.
+type Aggregator struct {
+	mu      sync.Mutex
+	samples map[string][]sensor.Data
+}
+
+// New creates an empty aggregator
+func New() *Aggregator {
+	return &Aggregator{
+		samples: make(map[string][]sensor.Data),
+	}
+}
+
+// Add adds a measurement to the aggregator
+func (a *Aggregator) Add(sd sensor.Data) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.samples[sd.Addr] = append(a.samples[sd.Addr], sd)
+}
+
+// Flush returns the aggregated measurements of each peripheral and resets the aggregator.
+// A measurement is returned for each peripheral and aggregation function, labeled with the function and
+// the number of aggregated samples. Name, address and timestamp are taken from the last sample.
+func (a *Aggregator) Flush(funcs []Func) []sensor.Data {
+	a.mu.Lock()
+	samples := a.samples
+	a.samples = make(map[string][]sensor.Data)
+	a.mu.Unlock()
+	addrs := make([]string, 0, len(samples))
+	for addr := range samples {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	var results []sensor.Data
+	for _, addr := range addrs {
+		for _, f := range funcs {
+			results = append(results, Aggregate(samples[addr], f))
+		}
+	}
+	return results
+}
+
+// measured contains the fields of measured quantities, which are aggregated with the aggregation function.
+// Other numeric fields, such as the movement counter and the measurement sequence number, are carried
+// over as their last value since their mean, minimum or maximum is meaningless.
+var measured = map[string]bool{
+	"Temperature":          true,
+	"Humidity":             true,
+	"DewPoint":             true,
+	"Pressure":             true,
+	"BatteryVoltage":       true,
+	"AccelerationX":        true,
+	"AccelerationY":        true,
+	"AccelerationZ":        true,
+	"PM1":                  true,
+	"PM25":                 true,
+	"PM4":                  true,
+	"PM10":                 true,
+	"CO2":                  true,
+	"VOC":                  true,
+	"NOX":                  true,
+	"Luminosity":           true,
+	"SoundInstant":         true,
+	"SoundAverage":         true,
+	"SoundPeak":            true,
+	"AbsoluteHumidity":     true,
+	"VaporPressureDeficit": true,
+	"HumidityRatio":        true,
+	"WetBulb":              true,
+	"FrostPoint":           true,
+	"HeatIndex":            true,
+	"AirDensity":           true,
+	"RawTemperature":       true,
+	"RawHumidity":          true,
+	"RawPressure":          true,
+}
+
+// Aggregate applies the aggregation function to each measured quantity of the measurements and takes the
+// last value of the other numeric fields. Missing values are ignored, and a field is missing from the result
+// if it is missing from every sample.
+func Aggregate(samples []sensor.Data, f Func) sensor.Data {
+	if len(samples) == 0 {
+		return sensor.Data{}
+	}
+	result := samples[len(samples)-1]
+	if f != Last {
+		result.RawData = ""
+	}
+	rv := reflect.ValueOf(&result).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		if !isNumeric(field.Type()) {
+			continue
+		}
+		var values []float64
+		for _, sd := range samples {
+			v := reflect.ValueOf(sd).Field(i)
+			if v.IsNil() {
+				continue
+			}
+			if v.Elem().Kind() == reflect.Int {
+				values = append(values, float64(v.Elem().Int()))
+			} else {
+				values = append(values, v.Elem().Float())
+			}
+		}
+		if len(values) == 0 {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		fn := f
+		if !measured[rv.Type().Field(i).Name] {
+			fn = Last
+		}
+		agg := apply(fn, values)
+		if field.Type().Elem().Kind() == reflect.Int {
+			field.Set(reflect.ValueOf(sensor.Int(int(math.Round(agg)))))
+		} else {
+			field.Set(reflect.ValueOf(sensor.Float64(agg)))
+		}
+	}
+	result.Aggregate = string(f)
+	result.SampleCount = sensor.Int(len(samples))
+	return result
+}
+
+func apply(f Func, values []float64) float64 {
+	switch f {
+	case Mean:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	case Min:
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	case Max:
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	default:
+		return values[len(values)-1]
+	}
+}
+
+func isNumeric(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer {
+		return false
+	}
+	k := t.Elem().Kind()
+	return k == reflect.Float64 || k == reflect.Int
+}
+
+func isSupported(f Func) bool {
+	for _, supported := range AllFuncs {
+		if f == supported {
+			return true
+		}
+	}
+	return false
+}
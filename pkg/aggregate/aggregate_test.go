@@ -0,0 +1,105 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+var (
+	ts      = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	samples = []sensor.Data{
+		{
+			Addr:            "cc:ca:7e:52:cc:34",
+			Name:            "Backyard",
+			Temperature:     sensor.Float64(20.0),
+			Humidity:        sensor.Float64(40.0),
+			MovementCounter: sensor.Int(1),
+			RSSI:            sensor.Int(-60),
+			RawData:         "990405",
+			Timestamp:       ts,
+		},
+		{
+			Addr:            "cc:ca:7e:52:cc:34",
+			Name:            "Backyard",
+			Temperature:     sensor.Float64(22.0),
+			MovementCounter: sensor.Int(3),
+			RSSI:            sensor.Int(-90),
+			RawData:         "990405",
+			Timestamp:       ts.Add(time.Second),
+		},
+		{
+			Addr:            "cc:ca:7e:52:cc:34",
+			Name:            "Backyard",
+			Temperature:     sensor.Float64(27.0),
+			Humidity:        sensor.Float64(44.0),
+			MovementCounter: sensor.Int(4),
+			RawData:         "990405",
+			Timestamp:       ts.Add(2 * time.Second),
+		},
+	}
+)
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		f           Func
+		temperature float64
+		humidity    float64
+	}{
+		{Mean, 23.0, 42.0},
+		{Min, 20.0, 40.0},
+		{Max, 27.0, 44.0},
+		{Last, 27.0, 44.0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.f), func(t *testing.T) {
+			sd := Aggregate(samples, tt.f)
+			assert.Equal(t, "Backyard", sd.Name)
+			assert.Equal(t, ts.Add(2*time.Second), sd.Timestamp)
+			assert.Equal(t, string(tt.f), sd.Aggregate)
+			assert.Equal(t, sensor.Int(3), sd.SampleCount)
+			assert.Equal(t, sensor.Float64(tt.temperature), sd.Temperature)
+			assert.Equal(t, sensor.Float64(tt.humidity), sd.Humidity)
+			assert.Equal(t, sensor.Int(4), sd.MovementCounter, "counters take the last value")
+			assert.Equal(t, sensor.Int(-90), sd.RSSI, "the last value of fields missing from the last sample is used")
+			assert.Nil(t, sd.Pressure)
+		})
+	}
+}
+
+func TestAggregateKeepsRawDataOnlyForLast(t *testing.T) {
+	assert.Empty(t, Aggregate(samples, Mean).RawData)
+	assert.Equal(t, "990405", Aggregate(samples, Last).RawData)
+}
+
+func TestFlush(t *testing.T) {
+	agg := New()
+	agg.Add(sensor.Data{Addr: "fb:e1:b7:04:95:ee", Temperature: sensor.Float64(10.0)})
+	for _, sd := range samples {
+		agg.Add(sd)
+	}
+	results := agg.Flush([]Func{Mean, Max})
+	require.Len(t, results, 4)
+	assert.Equal(t, "cc:ca:7e:52:cc:34", results[0].Addr)
+	assert.Equal(t, "mean", results[0].Aggregate)
+	assert.Equal(t, "cc:ca:7e:52:cc:34", results[1].Addr)
+	assert.Equal(t, "max", results[1].Aggregate)
+	assert.Equal(t, "fb:e1:b7:04:95:ee", results[2].Addr)
+	assert.Equal(t, sensor.Int(1), results[2].SampleCount)
+	assert.Empty(t, agg.Flush([]Func{Mean}))
+}
+
+func TestParseFuncs(t *testing.T) {
+	funcs, err := ParseFuncs([]string{"mean", "max"})
+	require.NoError(t, err)
+	assert.Equal(t, []Func{Mean, Max}, funcs)
+	funcs, err = ParseFuncs([]string{"all"})
+	require.NoError(t, err)
+	assert.Equal(t, AllFuncs, funcs)
+	_, err = ParseFuncs([]string{"median"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package calibration
+
+import (
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// Linear is a linear correction of the form value*Slope + Offset. Zero Slope is treated as 1.
+type Linear struct {
+	Offset float64
+	Slope  float64
+}
+
+// Apply returns the corrected value
+func (l Linear) Apply(v float64) float64 {
+	slope := l.Slope
+	if slope == 0 {
+		slope = 1
+	}
+	return v*slope + l.Offset
+}
+
+// IsIdentity returns true if the correction does not change values
+func (l Linear) IsIdentity() bool {
+	return l.Offset == 0 && (l.Slope == 0 || l.Slope == 1)
+}
+
+// Calibration contains the corrections of a single RuuviTag
+type Calibration struct {
+	Temperature Linear
+	Humidity    Linear
+	Pressure    Linear
+	// KeepRaw stores the uncorrected values in the raw fields of sensor.Data
+	KeepRaw bool
+}
+
+// IsIdentity returns true if the calibration does not change values
+func (c Calibration) IsIdentity() bool {
+	return c.Temperature.IsIdentity() && c.Humidity.IsIdentity() && c.Pressure.IsIdentity()
+}
+
+// Apply corrects the temperature, humidity and pressure of the given sensor data in place.
+// Corrected humidity is limited to 0–100%.
+func (c Calibration) Apply(sd *sensor.Data) {
+	if c.IsIdentity() {
+		return
+	}
+	if c.KeepRaw {
+		sd.RawTemperature = sd.Temperature
+		sd.RawHumidity = sd.Humidity
+		sd.RawPressure = sd.Pressure
+	}
+	sd.Temperature = apply(c.Temperature, sd.Temperature)
+	sd.Humidity = apply(c.Humidity, sd.Humidity)
+	if sd.Humidity != nil {
+		sd.Humidity = sensor.Float64(min(max(*sd.Humidity, 0), 100))
+	}
+	sd.Pressure = apply(c.Pressure, sd.Pressure)
+}
+
+func apply(l Linear, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	return sensor.Float64(l.Apply(*v))
+}
@@ -0,0 +1,54 @@
+package calibration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func TestLinear(t *testing.T) {
+	assert.Equal(t, 20.5, Linear{Offset: 0.5}.Apply(20))
+	assert.InDelta(t, 20.7, Linear{Offset: 0.5, Slope: 1.01}.Apply(20), 0.0001)
+	assert.True(t, Linear{}.IsIdentity())
+	assert.True(t, Linear{Slope: 1}.IsIdentity())
+	assert.False(t, Linear{Slope: 1.01}.IsIdentity())
+}
+
+func TestApply(t *testing.T) {
+	c := Calibration{
+		Temperature: Linear{Offset: -0.8},
+		Humidity:    Linear{Offset: 4, Slope: 1.02},
+	}
+	sd := sensor.Data{
+		Temperature: sensor.Float64(21.3),
+		Humidity:    sensor.Float64(50),
+		Pressure:    sensor.Float64(1002),
+	}
+	c.Apply(&sd)
+	require.NotNil(t, sd.Temperature)
+	assert.InDelta(t, 20.5, *sd.Temperature, 0.0001)
+	assert.InDelta(t, 55.0, *sd.Humidity, 0.0001)
+	assert.Equal(t, 1002.0, *sd.Pressure)
+	assert.Nil(t, sd.RawTemperature)
+}
+
+func TestApplyKeepRaw(t *testing.T) {
+	c := Calibration{
+		Temperature: Linear{Offset: -0.8},
+		Humidity:    Linear{Offset: 4},
+		KeepRaw:     true,
+	}
+	sd := sensor.Data{
+		Temperature: sensor.Float64(21.3),
+		Humidity:    sensor.Float64(98),
+	}
+	c.Apply(&sd)
+	assert.Equal(t, 21.3, *sd.RawTemperature)
+	assert.Equal(t, 98.0, *sd.RawHumidity)
+	assert.Nil(t, sd.RawPressure)
+	assert.Equal(t, 100.0, *sd.Humidity, "humidity is limited to 100%")
+	assert.Nil(t, sd.Pressure)
+}
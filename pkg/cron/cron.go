@@ -0,0 +1,234 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a parsed cron expression with fields for seconds, minutes, hours, day of month, month
+// and day of week
+type Expression struct {
+	second   uint64
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	location *time.Location
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	dom     = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dow = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// maxYears limits how far into the future Next searches for a matching time
+const maxYears = 5
+
+// Parse parses a cron expression. The expression has six fields (second, minute, hour, day of month,
+// month and day of week) or five fields without seconds, in which case scans run at the start of the
+// minute. Fields support lists (1,2), ranges (1-5), steps (*/15, 10-40/10) and month and weekday names.
+// The descriptors @yearly, @monthly, @weekly, @daily and @hourly are also supported. The expression can
+// be prefixed with a time zone, e.g. "CRON_TZ=Europe/Helsinki 0 30 6 * * *"; by default local time is used.
+func Parse(spec string) (*Expression, error) {
+	spec = strings.TrimSpace(spec)
+	loc := time.Local
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i == -1 {
+			return nil, fmt.Errorf("missing fields after time zone: %s", spec)
+		}
+		var err error
+		loc, err = time.LoadLocation(spec[strings.Index(spec, "=")+1 : i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone: %w", err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+	if d, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields, found %d: %s", len(fields), spec)
+	}
+	e := &Expression{location: loc}
+	var err error
+	if e.second, err = parseField(fields[0], seconds); err != nil {
+		return nil, fmt.Errorf("second: %w", err)
+	}
+	if e.minute, err = parseField(fields[1], minutes); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if e.hour, err = parseField(fields[2], hours); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if e.dom, err = parseField(fields[3], dom); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if e.month, err = parseField(fields[4], months); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if e.dow, err = parseField(fields[5], dow); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// Sunday can be given either as 0 or 7
+	if e.dow&(1<<7) != 0 {
+		e.dow |= 1
+	}
+	e.domStar = isStar(fields[3])
+	e.dowStar = isStar(fields[5])
+	return e, nil
+}
+
+// Next returns the first time matching the expression that is strictly after t, or the zero time if
+// there is no such time within a few years
+func (e *Expression) Next(t time.Time) time.Time {
+	t = t.In(e.location).Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + maxYears
+	for t.Year() <= limit {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, e.location)
+			continue
+		}
+		if !e.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, e.location)
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, e.location)
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if e.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Location returns the time zone of the expression
+func (e *Expression) Location() *time.Location {
+	return e.location
+}
+
+// dayMatches reports whether the day of t matches the expression. Like in standard cron, if both the day of
+// month and the day of week are restricted, a day matching either of them matches.
+func (e *Expression) dayMatches(t time.Time) bool {
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+	if !e.domStar && !e.dowStar {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+func isStar(field string) bool {
+	return field == "*" || field == "?"
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		itemBits, err := parseItem(item, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= itemBits
+	}
+	return bits, nil
+}
+
+func parseItem(item string, b bounds) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step: %s", item)
+		}
+	}
+	var start, end int
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		start, end = b.min, b.max
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = parseValue(lo, b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(hi, b); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range: %s", item)
+		}
+	default:
+		var err error
+		if start, err = parseValue(rangePart, b); err != nil {
+			return 0, err
+		}
+		end = start
+		if hasStep {
+			end = b.max
+		}
+	}
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %s", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
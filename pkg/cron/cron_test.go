@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 12, 1, 12, 321, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * * *", time.Date(2024, time.January, 31, 12, 1, 13, 0, time.UTC)},
+		{"*/15 * * * * *", time.Date(2024, time.January, 31, 12, 1, 15, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2024, time.January, 31, 12, 5, 0, 0, time.UTC)},
+		{"30 0 */2 * * *", time.Date(2024, time.January, 31, 14, 0, 30, 0, time.UTC)},
+		{"0 30 0,12 * * *", time.Date(2024, time.January, 31, 12, 30, 0, 0, time.UTC)},
+		{"0 0 9-17/4 * * *", time.Date(2024, time.January, 31, 13, 0, 0, 0, time.UTC)},
+		{"0 0 0 * * *", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 13, 0, 0, 0, time.UTC)},
+		{"0 0 0 29 FEB *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 0 31 * *", time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 8 * * MON-FRI", time.Date(2024, time.February, 1, 8, 0, 0, 0, time.UTC)},
+		{"0 0 8 * * 7", time.Date(2024, time.February, 4, 8, 0, 0, 0, time.UTC)},
+		{"0 0 0 15 * SAT", time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 0 1 1 *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			e, err := Parse(tt.spec)
+			require.NoError(t, err)
+			e.location = time.UTC
+			assert.Equal(t, tt.next, e.Next(from))
+		})
+	}
+}
+
+func TestNextIsStrictlyAfter(t *testing.T) {
+	e, err := Parse("TZ=UTC */10 * * * * *")
+	require.NoError(t, err)
+	ts := time.Date(2024, time.January, 1, 12, 0, 10, 0, time.UTC)
+	assert.Equal(t, ts.Add(10*time.Second), e.Next(ts))
+}
+
+func TestTimeZone(t *testing.T) {
+	e, err := Parse("CRON_TZ=Europe/Helsinki 0 30 6 * * *")
+	require.NoError(t, err)
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	require.NoError(t, err)
+	assert.Equal(t, helsinki, e.Location())
+	next := e.Next(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+	assert.True(t, time.Date(2024, time.January, 2, 4, 30, 0, 0, time.UTC).Equal(next))
+}
+
+func TestDaylightSavingTime(t *testing.T) {
+	// Clocks in Helsinki jump from 03:00 to 04:00 on 31 March 2024
+	e, err := Parse("CRON_TZ=Europe/Helsinki 0 30 3 * * *")
+	require.NoError(t, err)
+	next := e.Next(time.Date(2024, time.March, 30, 12, 0, 0, 0, time.UTC))
+	assert.True(t, time.Date(2024, time.April, 1, 0, 30, 0, 0, time.UTC).Equal(next), next.UTC().String())
+}
+
+func TestNoMatch(t *testing.T) {
+	e, err := Parse("0 0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, e.Next(time.Now()).IsZero())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"*/0 * * * * *",
+		"5-1 * * * * *",
+		"a * * * * *",
+		"TZ=Nowhere/City * * * * *",
+		"TZ=UTC",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
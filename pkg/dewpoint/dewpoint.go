@@ -43,18 +43,21 @@ func Calculate(temp float64, unit temperature.Unit, humidity float64) (float64,
 	if tempInK < MinTemperature || tempInK > MaxTemperature {
 		return 0, fmt.Errorf("temperature %f %v out of range", temp, unit)
 	}
-	dpInK, err := Solve(pvs, humidity/100.0*pvs(tempInK), tempInK)
+	dpInK, err := Solve(SaturationVaporPressure, humidity/100.0*SaturationVaporPressure(tempInK), tempInK)
 	return temperature.Convert(dpInK, temperature.Kelvin, unit), err
 }
 
-func pvs(tempInK float64) float64 {
+// SaturationVaporPressure returns the saturation vapor pressure in pascals at the given temperature in kelvins.
+// The pressure is calculated over ice below the freezing point and over water above it.
+func SaturationVaporPressure(tempInK float64) float64 {
 	if tempInK < temperature.CelsiusOffset {
-		return pvsIce(tempInK)
+		return SaturationVaporPressureIce(tempInK)
 	}
-	return pvsWater(tempInK)
+	return SaturationVaporPressureWater(tempInK)
 }
 
-func pvsWater(tempInK float64) float64 {
+// SaturationVaporPressureWater returns the saturation vapor pressure over water in pascals
+func SaturationVaporPressureWater(tempInK float64) float64 {
 	th := tempInK + N9/(tempInK-N10)
 	a := (th+N1)*th + N2
 	b := (N3*th+N4)*th + N5
@@ -66,7 +69,8 @@ func pvsWater(tempInK float64) float64 {
 	return p * 1e6
 }
 
-func pvsIce(tempInK float64) float64 {
+// SaturationVaporPressureIce returns the saturation vapor pressure over ice in pascals
+func SaturationVaporPressureIce(tempInK float64) float64 {
 	lnP := K0/tempInK + K1 + (K2+(K3+(K4*tempInK))*tempInK)*tempInK + K5*math.Log(tempInK)
 	return math.Exp(lnP)
 }
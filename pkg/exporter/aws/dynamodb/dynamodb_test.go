@@ -40,16 +40,46 @@ func TestExport(t *testing.T) {
 	data := sensor.Data{
 		Addr:            "CC:CA:7E:52:CC:34",
 		Name:            "Backyard",
-		Temperature:     21.5,
-		Humidity:        60,
-		Pressure:        1002,
-		BatteryVoltage:  50,
-		AccelerationX:   0,
-		AccelerationY:   0,
-		AccelerationZ:   0,
-		MovementCounter: 1,
+		Temperature:     sensor.Float64(21.5),
+		Humidity:        sensor.Float64(60),
+		Pressure:        sensor.Float64(1002),
+		BatteryVoltage:  sensor.Float64(50),
+		AccelerationX:   sensor.Int(0),
+		AccelerationY:   sensor.Int(0),
+		AccelerationZ:   sensor.Int(0),
+		MovementCounter: sensor.Int(1),
 		Timestamp:       time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
 	}
 	err := exp.Export(ctx, data)
 	require.NoError(t, err)
 }
+
+type recordingDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	input *dynamodb.PutItemInput
+}
+
+func (m *recordingDynamoDBClient) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.input = input
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestExportMissingValues(t *testing.T) {
+	client := new(recordingDynamoDBClient)
+	exp := &dynamoDBExporter{
+		db:    client,
+		table: "test_table",
+	}
+	data := sensor.Data{
+		Addr:      "CC:CA:7E:52:CC:34",
+		Name:      "Backyard",
+		Humidity:  sensor.Float64(60),
+		Timestamp: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	err := exp.Export(context.Background(), data)
+	require.NoError(t, err)
+	require.NotNil(t, client.input)
+	assert.Equal(t, "60", *client.input.Item["humidity"].N)
+	assert.NotContains(t, client.input.Item, "temperature")
+	assert.NotContains(t, client.input.Item, "pressure")
+}
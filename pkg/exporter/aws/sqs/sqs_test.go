@@ -26,7 +26,7 @@ func (m *mockSQSClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendM
 	assert := assert.New(m.t)
 	assert.Equal("CC:CA:7E:52:CC:34", *input.MessageAttributes["mac"].StringValue)
 	assert.Equal("Backyard", *input.MessageAttributes["name"].StringValue)
-	assert.Equal("{\"mac\":\"CC:CA:7E:52:CC:34\",\"name\":\"Backyard\",\"temperature\":21.5,\"humidity\":60,\"pressure\":1002,\"battery_voltage\":50,\"acceleration_x\":0,\"acceleration_y\":0,\"acceleration_z\":0,\"movement_counter\":1,\"measurement_number\":0,\"ts\":\"2020-01-01T00:00:00Z\"}", *input.MessageBody)
+	assert.Equal("{\"mac\":\"CC:CA:7E:52:CC:34\",\"name\":\"Backyard\",\"temperature\":21.5,\"humidity\":60,\"pressure\":1002,\"battery_voltage\":50,\"acceleration_x\":0,\"acceleration_y\":0,\"acceleration_z\":0,\"movement_counter\":1,\"ts\":\"2020-01-01T00:00:00Z\"}", *input.MessageBody)
 	return &sqs.SendMessageOutput{}, nil
 }
 
@@ -39,14 +39,14 @@ func TestExport(t *testing.T) {
 	data := sensor.Data{
 		Addr:            "CC:CA:7E:52:CC:34",
 		Name:            "Backyard",
-		Temperature:     21.5,
-		Humidity:        60,
-		Pressure:        1002,
-		BatteryVoltage:  50,
-		AccelerationX:   0,
-		AccelerationY:   0,
-		AccelerationZ:   0,
-		MovementCounter: 1,
+		Temperature:     sensor.Float64(21.5),
+		Humidity:        sensor.Float64(60),
+		Pressure:        sensor.Float64(1002),
+		BatteryVoltage:  sensor.Float64(50),
+		AccelerationX:   sensor.Int(0),
+		AccelerationY:   sensor.Int(0),
+		AccelerationZ:   sensor.Int(0),
+		MovementCounter: sensor.Int(1),
 		Timestamp:       time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
 	}
 	err := exp.Export(ctx, data)
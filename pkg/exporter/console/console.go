@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
 )
 
@@ -24,6 +25,15 @@ func (e Exporter) Export(ctx context.Context, data sensor.Data) error {
 	return nil
 }
 
+func (e Exporter) ExportEvent(ctx context.Context, event exporter.Event) error {
+	j, err := json.MarshalIndent(event, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(j))
+	return nil
+}
+
 func (e Exporter) Close() error {
 	return nil
 }
@@ -0,0 +1,30 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+type withoutDiagnostics struct {
+	Exporter
+}
+
+// WithoutDiagnostics wraps the exporter so that the advertisement diagnostics (RSSI, raw data, address type
+// and receiving adapter) are removed from measurements before exporting them
+func WithoutDiagnostics(e Exporter) Exporter {
+	return withoutDiagnostics{Exporter: e}
+}
+
+func (e withoutDiagnostics) Export(ctx context.Context, data sensor.Data) error {
+	data.RSSI = nil
+	data.RawData = ""
+	data.AddressType = ""
+	data.Adapter = ""
+	return e.Exporter.Export(ctx, data)
+}
+
+// Unwrap returns the wrapped exporter
+func (e withoutDiagnostics) Unwrap() Exporter {
+	return e.Exporter
+}
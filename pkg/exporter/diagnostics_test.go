@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+type recordingExporter struct {
+	NoOp
+	data []sensor.Data
+}
+
+func (e *recordingExporter) Export(ctx context.Context, data sensor.Data) error {
+	e.data = append(e.data, data)
+	return nil
+}
+
+func TestWithoutDiagnostics(t *testing.T) {
+	rec := &recordingExporter{NoOp: NoOp{ReportedName: "Recording"}}
+	exp := WithoutDiagnostics(rec)
+	assert.Equal(t, "Recording", exp.Name())
+	err := exp.Export(context.Background(), sensor.Data{
+		Addr:        "cc:ca:7e:52:cc:34",
+		Temperature: sensor.Float64(21.5),
+		RSSI:        sensor.Int(-70),
+		RawData:     "99040512fc",
+		AddressType: "random",
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.data, 1)
+	assert.Equal(t, 21.5, *rec.data[0].Temperature)
+	assert.Nil(t, rec.data[0].RSSI)
+	assert.Empty(t, rec.data[0].RawData)
+	assert.Empty(t, rec.data[0].AddressType)
+}
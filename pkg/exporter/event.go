@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"context"
+	"time"
+)
+
+// EventType is the type of an event about a peripheral
+type EventType string
+
+const (
+	// TagMissing is emitted when no measurements have been received from a peripheral within the staleness threshold
+	TagMissing EventType = "tag_missing"
+	// TagRecovered is emitted when a measurement is received from a peripheral that was missing
+	TagRecovered EventType = "tag_recovered"
+	// Motion is emitted when a peripheral has moved since its previous measurement
+	Motion EventType = "motion"
+)
+
+// Event is an event about a peripheral
+type Event struct {
+	Type      EventType `json:"type"`
+	Addr      string    `json:"mac"`
+	Name      string    `json:"name"`
+	LastSeen  time.Time `json:"last_seen"`
+	Timestamp time.Time `json:"ts"`
+	// Movements is how many times the movement counter increased since the previous measurement, for motion events
+	Movements int `json:"movements,omitempty"`
+	// AccelerationChange is the change in acceleration in mG since the previous measurement, for motion events
+	AccelerationChange float64 `json:"acceleration_change,omitempty"`
+}
+
+// EventExporter is implemented by exporters that can deliver events in addition to measurements
+type EventExporter interface {
+	ExportEvent(ctx context.Context, event Event) error
+}
+
+// AsEventExporter returns the exporter as an EventExporter if it, or the exporter it wraps, supports events
+func AsEventExporter(e Exporter) (EventExporter, bool) {
+	for {
+		if ee, ok := e.(EventExporter); ok {
+			return ee, true
+		}
+		w, ok := e.(interface{ Unwrap() Exporter })
+		if !ok {
+			return nil, false
+		}
+		e = w.Unwrap()
+	}
+}
@@ -0,0 +1,28 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type eventExporter struct {
+	NoOp
+}
+
+func (e eventExporter) ExportEvent(ctx context.Context, event Event) error {
+	return nil
+}
+
+func TestAsEventExporter(t *testing.T) {
+	_, ok := AsEventExporter(NoOp{})
+	assert.False(t, ok)
+	_, ok = AsEventExporter(WithoutDiagnostics(NoOp{}))
+	assert.False(t, ok)
+	_, ok = AsEventExporter(eventExporter{})
+	assert.True(t, ok)
+	ee, ok := AsEventExporter(WithoutDiagnostics(eventExporter{}))
+	assert.True(t, ok)
+	assert.Equal(t, eventExporter{}, ee)
+}
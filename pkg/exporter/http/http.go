@@ -37,9 +37,19 @@ func (h httpExporter) Name() string {
 }
 
 func (h httpExporter) Export(ctx context.Context, data sensor.Data) error {
+	return h.post(ctx, data)
+}
+
+// ExportEvent sends the event as JSON to the same endpoint as measurements. Events can be told apart
+// from measurements by their type field.
+func (h httpExporter) ExportEvent(ctx context.Context, event exporter.Event) error {
+	return h.post(ctx, event)
+}
+
+func (h httpExporter) post(ctx context.Context, v any) error {
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
-	err := enc.Encode(data)
+	err := enc.Encode(v)
 	if err != nil {
 		return err
 	}
@@ -43,25 +43,68 @@ func (e *influxdbExporter) Name() string {
 }
 
 func (e *influxdbExporter) Export(ctx context.Context, data sensor.Data) error {
-	point := influxdb2.NewPoint(e.measurement, map[string]string{
+	fields := make(map[string]interface{})
+	addField(fields, "temperature", data.Temperature)
+	addField(fields, "humidity", data.Humidity)
+	addField(fields, "dew_point", data.DewPoint)
+	addField(fields, "pressure", data.Pressure)
+	addField(fields, "battery_voltage", data.BatteryVoltage)
+	addField(fields, "tx_power", data.TxPower)
+	addField(fields, "acceleration_x", data.AccelerationX)
+	addField(fields, "acceleration_y", data.AccelerationY)
+	addField(fields, "acceleration_z", data.AccelerationZ)
+	addField(fields, "movement_counter", data.MovementCounter)
+	addField(fields, "measurement_number", data.MeasurementNumber)
+	addField(fields, "pm1_0", data.PM1)
+	addField(fields, "pm2_5", data.PM25)
+	addField(fields, "pm4_0", data.PM4)
+	addField(fields, "pm10_0", data.PM10)
+	addField(fields, "co2", data.CO2)
+	addField(fields, "voc", data.VOC)
+	addField(fields, "nox", data.NOX)
+	addField(fields, "luminosity", data.Luminosity)
+	addField(fields, "sound_instant", data.SoundInstant)
+	addField(fields, "sound_average", data.SoundAverage)
+	addField(fields, "sound_peak", data.SoundPeak)
+	addField(fields, "absolute_humidity", data.AbsoluteHumidity)
+	addField(fields, "vapor_pressure_deficit", data.VaporPressureDeficit)
+	addField(fields, "humidity_ratio", data.HumidityRatio)
+	addField(fields, "wet_bulb", data.WetBulb)
+	addField(fields, "frost_point", data.FrostPoint)
+	addField(fields, "heat_index", data.HeatIndex)
+	addField(fields, "air_density", data.AirDensity)
+	addField(fields, "raw_temperature", data.RawTemperature)
+	addField(fields, "raw_humidity", data.RawHumidity)
+	addField(fields, "raw_pressure", data.RawPressure)
+	addField(fields, "rssi", data.RSSI)
+	if data.RawData != "" {
+		fields["raw_data"] = data.RawData
+	}
+	if data.AddressType != "" {
+		fields["address_type"] = data.AddressType
+	}
+	if data.Adapter != "" {
+		fields["adapter"] = data.Adapter
+	}
+	addField(fields, "sample_count", data.SampleCount)
+	tags := map[string]string{
 		"mac":  strings.ToUpper(data.Addr),
 		"name": data.Name,
-	}, map[string]interface{}{
-		"temperature":        data.Temperature,
-		"humidity":           data.Humidity,
-		"dew_point":          data.DewPoint,
-		"pressure":           data.Pressure,
-		"battery_voltage":    data.BatteryVoltage,
-		"tx_power":           data.TxPower,
-		"acceleration_x":     data.AccelerationX,
-		"acceleration_y":     data.AccelerationY,
-		"acceleration_z":     data.AccelerationZ,
-		"movement_counter":   data.MovementCounter,
-		"measurement_number": data.MeasurementNumber,
-	}, data.Timestamp)
+	}
+	if data.Aggregate != "" {
+		tags["aggregate"] = data.Aggregate
+	}
+	point := influxdb2.NewPoint(e.measurement, tags, fields, data.Timestamp)
 	return e.writeAPI.WritePoint(ctx, point)
 }
 
+// addField adds the given value to fields unless it is missing
+func addField[T int | float64](fields map[string]interface{}, name string, v *T) {
+	if v != nil {
+		fields[name] = *v
+	}
+}
+
 func (e *influxdbExporter) Close() error {
 	e.client.Close()
 	return nil
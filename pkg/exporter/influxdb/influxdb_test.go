@@ -64,14 +64,14 @@ func TestExporter(t *testing.T) {
 		err := exporter.Export(context.Background(), sensor.Data{
 			Addr:           "CC:CA:7E:52:CC:34",
 			Name:           "Backyard",
-			Temperature:    22.1,
-			Humidity:       45.0,
-			DewPoint:       9.6,
-			Pressure:       1002.0,
-			BatteryVoltage: 2.755,
-			AccelerationX:  0,
-			AccelerationY:  0,
-			AccelerationZ:  0,
+			Temperature:    sensor.Float64(22.1),
+			Humidity:       sensor.Float64(45.0),
+			DewPoint:       sensor.Float64(9.6),
+			Pressure:       sensor.Float64(1002.0),
+			BatteryVoltage: sensor.Float64(2.755),
+			AccelerationX:  sensor.Int(0),
+			AccelerationY:  sensor.Int(0),
+			AccelerationZ:  sensor.Int(0),
 			Timestamp:      time.Now(),
 		})
 		require.NoError(t, err)
@@ -83,6 +83,21 @@ func (m mqttExporter) Export(ctx context.Context, data sensor.Data) error {
 	return token.Error()
 }
 
+// ExportEvent publishes the event to the events topic of the peripheral
+func (m mqttExporter) ExportEvent(ctx context.Context, event exporter.Event) error {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	err := enc.Encode(event)
+	if err != nil {
+		return err
+	}
+	mac := strings.Replace(event.Addr, ":", "", -1)
+	topic := fmt.Sprintf("ruuvitag-gollector/%s/%s/events", event.Name, mac)
+	token := m.client.Publish(topic, 0, false, buf.String())
+	token.Wait()
+	return token.Error()
+}
+
 func (m mqttExporter) Close() error {
 	m.client.Disconnect(0)
 	return nil
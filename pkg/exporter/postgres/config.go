@@ -0,0 +1,23 @@
+package postgres
+
+// Config contains the settings of the PostgreSQL exporter
+type Config struct {
+	ConnString string
+	Table      string
+	// AirQuality enables storing the particulate matter, CO2, VOC, NOx, luminosity and sound measurements
+	// of Ruuvi Air.
+	// The table must have the columns in AirQualitySchemaTmpl.
+	AirQuality bool
+	// DerivedMetrics enables storing derived psychrometric metrics.
+	// The table must have the columns in DerivedMetricsSchemaTmpl.
+	DerivedMetrics bool
+	// RawValues enables storing the raw values of calibrated measurements.
+	// The table must have the columns in RawValuesSchemaTmpl.
+	RawValues bool
+	// Aggregates enables storing the aggregation function and sample count of aggregated measurements.
+	// The table must have the columns in AggregatesSchemaTmpl.
+	Aggregates bool
+	// Diagnostics enables storing RSSI, raw data, address type and receiving adapter of advertisements.
+	// The table must have the columns in DiagnosticsSchemaTmpl.
+	Diagnostics bool
+}
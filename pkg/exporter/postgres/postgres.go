@@ -5,7 +5,9 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
@@ -13,7 +15,7 @@ import (
 	_ "github.com/lib/pq"
 )
 
-const SchemaTmpl = `CREATE TABLE %s (
+const SchemaTmpl = `CREATE TABLE IF NOT EXISTS %s (
   id BIGSERIAL PRIMARY KEY,
   mac MACADDR NOT NULL,
   name TEXT,
@@ -29,38 +31,188 @@ const SchemaTmpl = `CREATE TABLE %s (
   measurement_number INTEGER
 )`
 
+// AirQualitySchemaTmpl adds the columns needed for storing the air quality measurements of Ruuvi Air
+const AirQualitySchemaTmpl = `ALTER TABLE %s
+  ADD COLUMN IF NOT EXISTS pm1_0 REAL,
+  ADD COLUMN IF NOT EXISTS pm2_5 REAL,
+  ADD COLUMN IF NOT EXISTS pm4_0 REAL,
+  ADD COLUMN IF NOT EXISTS pm10_0 REAL,
+  ADD COLUMN IF NOT EXISTS co2 INTEGER,
+  ADD COLUMN IF NOT EXISTS voc INTEGER,
+  ADD COLUMN IF NOT EXISTS nox INTEGER,
+  ADD COLUMN IF NOT EXISTS luminosity REAL,
+  ADD COLUMN IF NOT EXISTS sound_instant REAL,
+  ADD COLUMN IF NOT EXISTS sound_average REAL,
+  ADD COLUMN IF NOT EXISTS sound_peak REAL`
+
+// DerivedMetricsSchemaTmpl adds the columns needed for storing derived psychrometric metrics
+const DerivedMetricsSchemaTmpl = `ALTER TABLE %s
+  ADD COLUMN IF NOT EXISTS absolute_humidity REAL,
+  ADD COLUMN IF NOT EXISTS vapor_pressure_deficit REAL,
+  ADD COLUMN IF NOT EXISTS humidity_ratio REAL,
+  ADD COLUMN IF NOT EXISTS wet_bulb REAL,
+  ADD COLUMN IF NOT EXISTS frost_point REAL,
+  ADD COLUMN IF NOT EXISTS heat_index REAL,
+  ADD COLUMN IF NOT EXISTS air_density REAL`
+
+// RawValuesSchemaTmpl adds the columns needed for storing the raw values of calibrated measurements
+const RawValuesSchemaTmpl = `ALTER TABLE %s
+  ADD COLUMN IF NOT EXISTS raw_temperature REAL,
+  ADD COLUMN IF NOT EXISTS raw_humidity REAL,
+  ADD COLUMN IF NOT EXISTS raw_pressure REAL`
+
+// AggregatesSchemaTmpl adds the columns needed for storing aggregated measurements
+const AggregatesSchemaTmpl = `ALTER TABLE %s
+  ADD COLUMN IF NOT EXISTS aggregate TEXT,
+  ADD COLUMN IF NOT EXISTS sample_count INTEGER`
+
+// DiagnosticsSchemaTmpl adds the columns needed for storing advertisement diagnostics
+const DiagnosticsSchemaTmpl = `ALTER TABLE %s
+  ADD COLUMN IF NOT EXISTS rssi INTEGER,
+  ADD COLUMN IF NOT EXISTS raw_data TEXT,
+  ADD COLUMN IF NOT EXISTS address_type TEXT,
+  ADD COLUMN IF NOT EXISTS adapter TEXT`
+
+// column is a column of the measurements table and the value of a measurement stored in it
+type column struct {
+	name  string
+	value func(data sensor.Data) any
+}
+
+var baseColumns = []column{
+	{"mac", func(data sensor.Data) any { return data.Addr }},
+	{"name", func(data sensor.Data) any { return data.Name }},
+	{"ts", func(data sensor.Data) any { return data.Timestamp }},
+	{"temperature", func(data sensor.Data) any { return data.Temperature }},
+	{"humidity", func(data sensor.Data) any { return data.Humidity }},
+	{"pressure", func(data sensor.Data) any { return data.Pressure }},
+	{"acceleration_x", func(data sensor.Data) any { return data.AccelerationX }},
+	{"acceleration_y", func(data sensor.Data) any { return data.AccelerationY }},
+	{"acceleration_z", func(data sensor.Data) any { return data.AccelerationZ }},
+	{"movement_counter", func(data sensor.Data) any { return data.MovementCounter }},
+	{"battery", func(data sensor.Data) any { return data.BatteryVoltage }},
+	{"measurement_number", func(data sensor.Data) any { return data.MeasurementNumber }},
+}
+
+var airQualityColumns = []column{
+	{"pm1_0", func(data sensor.Data) any { return data.PM1 }},
+	{"pm2_5", func(data sensor.Data) any { return data.PM25 }},
+	{"pm4_0", func(data sensor.Data) any { return data.PM4 }},
+	{"pm10_0", func(data sensor.Data) any { return data.PM10 }},
+	{"co2", func(data sensor.Data) any { return data.CO2 }},
+	{"voc", func(data sensor.Data) any { return data.VOC }},
+	{"nox", func(data sensor.Data) any { return data.NOX }},
+	{"luminosity", func(data sensor.Data) any { return data.Luminosity }},
+	{"sound_instant", func(data sensor.Data) any { return data.SoundInstant }},
+	{"sound_average", func(data sensor.Data) any { return data.SoundAverage }},
+	{"sound_peak", func(data sensor.Data) any { return data.SoundPeak }},
+}
+
+var derivedMetricsColumns = []column{
+	{"absolute_humidity", func(data sensor.Data) any { return data.AbsoluteHumidity }},
+	{"vapor_pressure_deficit", func(data sensor.Data) any { return data.VaporPressureDeficit }},
+	{"humidity_ratio", func(data sensor.Data) any { return data.HumidityRatio }},
+	{"wet_bulb", func(data sensor.Data) any { return data.WetBulb }},
+	{"frost_point", func(data sensor.Data) any { return data.FrostPoint }},
+	{"heat_index", func(data sensor.Data) any { return data.HeatIndex }},
+	{"air_density", func(data sensor.Data) any { return data.AirDensity }},
+}
+
+var rawValuesColumns = []column{
+	{"raw_temperature", func(data sensor.Data) any { return data.RawTemperature }},
+	{"raw_humidity", func(data sensor.Data) any { return data.RawHumidity }},
+	{"raw_pressure", func(data sensor.Data) any { return data.RawPressure }},
+}
+
+var aggregatesColumns = []column{
+	{"aggregate", func(data sensor.Data) any { return nullString(data.Aggregate) }},
+	{"sample_count", func(data sensor.Data) any { return data.SampleCount }},
+}
+
+var diagnosticsColumns = []column{
+	{"rssi", func(data sensor.Data) any { return data.RSSI }},
+	{"raw_data", func(data sensor.Data) any { return nullString(data.RawData) }},
+	{"address_type", func(data sensor.Data) any { return nullString(data.AddressType) }},
+	{"adapter", func(data sensor.Data) any { return nullString(data.Adapter) }},
+}
+
+// Migrations returns the statements that add the columns of the enabled column groups to the table
+func Migrations(cfg Config) []string {
+	var tmpls []string
+	if cfg.AirQuality {
+		tmpls = append(tmpls, AirQualitySchemaTmpl)
+	}
+	if cfg.DerivedMetrics {
+		tmpls = append(tmpls, DerivedMetricsSchemaTmpl)
+	}
+	if cfg.RawValues {
+		tmpls = append(tmpls, RawValuesSchemaTmpl)
+	}
+	if cfg.Aggregates {
+		tmpls = append(tmpls, AggregatesSchemaTmpl)
+	}
+	if cfg.Diagnostics {
+		tmpls = append(tmpls, DiagnosticsSchemaTmpl)
+	}
+	stmts := make([]string, len(tmpls))
+	for i, tmpl := range tmpls {
+		stmts[i] = fmt.Sprintf(tmpl, cfg.Table)
+	}
+	return stmts
+}
+
+// columns returns the columns of the enabled column groups. Optional columns are only stored when enabled
+// so that tables created before the columns were added keep working.
+func columns(cfg Config) []column {
+	cols := append([]column(nil), baseColumns...)
+	if cfg.AirQuality {
+		cols = append(cols, airQualityColumns...)
+	}
+	if cfg.DerivedMetrics {
+		cols = append(cols, derivedMetricsColumns...)
+	}
+	if cfg.RawValues {
+		cols = append(cols, rawValuesColumns...)
+	}
+	if cfg.Aggregates {
+		cols = append(cols, aggregatesColumns...)
+	}
+	if cfg.Diagnostics {
+		cols = append(cols, diagnosticsColumns...)
+	}
+	return cols
+}
+
+func insertStatement(table string, cols []column) string {
+	names := make([]string, len(cols))
+	params := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.name
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), strings.Join(params, ", "))
+}
+
 type postgresExporter struct {
 	db         *sql.DB
 	insertStmt *sql.Stmt
+	columns    []column
 }
 
-func New(ctx context.Context, connStr, table string) (exporter.Exporter, error) {
-	db, err := sql.Open("postgres", connStr)
+func New(ctx context.Context, cfg Config) (exporter.Exporter, error) {
+	db, err := sql.Open("postgres", cfg.ConnString)
 	if err != nil {
 		return nil, err
 	}
-	insertStmt, err := db.PrepareContext(ctx, fmt.Sprintf(`
-INSERT INTO %s (
-  mac,
-  name,
-  ts,
-  temperature,
-  humidity,
-  pressure,
-  acceleration_x,
-  acceleration_y,
-  acceleration_z,
-  movement_counter,
-  battery,
-  measurement_number
-)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`, table))
+	cols := columns(cfg)
+	insertStmt, err := db.PrepareContext(ctx, insertStatement(cfg.Table, cols))
 	if err != nil {
 		return nil, err
 	}
 	return &postgresExporter{
 		db:         db,
 		insertStmt: insertStmt,
+		columns:    cols,
 	}, nil
 }
 
@@ -69,11 +221,18 @@ func (p *postgresExporter) Name() string {
 }
 
 func (p *postgresExporter) Export(ctx context.Context, data sensor.Data) error {
-	_, err := p.insertStmt.ExecContext(ctx, data.Addr, data.Name, data.Timestamp, data.Temperature, data.Humidity, data.Pressure, data.AccelerationX, data.AccelerationY, data.AccelerationZ, data.MovementCounter, data.BatteryVoltage, data.MeasurementNumber)
+	args := make([]any, len(p.columns))
+	for i, col := range p.columns {
+		args[i] = col.value(data)
+	}
+	_, err := p.insertStmt.ExecContext(ctx, args...)
 	return err
 }
 
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 func (p *postgresExporter) Close() error {
-	p.insertStmt.Close()
-	return p.db.Close()
+	return errors.Join(p.insertStmt.Close(), p.db.Close())
 }
@@ -0,0 +1,42 @@
+//go:build postgres
+
+package postgres
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertStatement(t *testing.T) {
+	stmt := insertStatement("measurements", columns(Config{}))
+	assert.Contains(t, stmt, "INSERT INTO measurements (mac, name, ts, temperature,")
+	assert.NotContains(t, stmt, "pm2_5", "optional columns are not stored by default")
+	assert.NotContains(t, stmt, "co2")
+	assert.NotContains(t, stmt, "wet_bulb")
+	assert.NotContains(t, stmt, "raw_temperature")
+	assert.NotContains(t, stmt, "sample_count")
+	assert.Contains(t, stmt, "measurement_number) VALUES")
+	assert.NotContains(t, stmt, "rssi")
+
+	cols := columns(Config{AirQuality: true, DerivedMetrics: true, RawValues: true, Aggregates: true, Diagnostics: true})
+	stmt = insertStatement("measurements", cols)
+	assert.Contains(t, stmt, "pm1_0, pm2_5, pm4_0, pm10_0, co2, voc, nox, luminosity, sound_instant, sound_average, sound_peak")
+	assert.Contains(t, stmt, "absolute_humidity, vapor_pressure_deficit, humidity_ratio, wet_bulb, frost_point, heat_index, air_density")
+	assert.Contains(t, stmt, "raw_temperature, raw_humidity, raw_pressure")
+	assert.Contains(t, stmt, "aggregate, sample_count")
+	assert.Contains(t, stmt, "rssi, raw_data, address_type, adapter)")
+	assert.Contains(t, stmt, "$1, $2")
+	assert.Contains(t, stmt, fmt.Sprintf("$%d)", len(cols)))
+}
+
+func TestMigrations(t *testing.T) {
+	assert.Empty(t, Migrations(Config{Table: "measurements"}))
+	stmts := Migrations(Config{Table: "measurements", AirQuality: true, DerivedMetrics: true, RawValues: true, Aggregates: true, Diagnostics: true})
+	assert.Len(t, stmts, 5)
+	for _, stmt := range stmts {
+		assert.Contains(t, stmt, "ALTER TABLE measurements")
+		assert.Contains(t, stmt, "ADD COLUMN IF NOT EXISTS")
+	}
+}
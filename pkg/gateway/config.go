@@ -0,0 +1,13 @@
+package gateway
+
+// SubscriberConfig configures the MQTT broker connection and the topics of a Subscriber
+type SubscriberConfig struct {
+	Addr     string
+	ClientId string
+	Username string
+	Password string
+	CaFile   string
+	// Topics are the topic filters to subscribe to. Defaults to DefaultTopic.
+	Topics []string
+	QoS    byte
+}
@@ -0,0 +1,191 @@
+// Package gateway receives advertisements relayed by Ruuvi Gateways
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+// manufacturerSpecificData is the AD type of manufacturer specific data in BLE advertisements
+const manufacturerSpecificData = 0xFF
+
+var ErrNoManufacturerData = errors.New("advertisement does not contain manufacturer data")
+
+// Payload is the JSON document a Ruuvi Gateway posts in its custom HTTP format
+type Payload struct {
+	Data PayloadData `json:"data"`
+}
+
+type PayloadData struct {
+	Coordinates string         `json:"coordinates"`
+	Timestamp   UnixTime       `json:"timestamp"`
+	GatewayMAC  string         `json:"gw_mac"`
+	Tags        map[string]Tag `json:"tags"`
+}
+
+// Tag is the latest advertisement of a tag seen by the gateway
+type Tag struct {
+	RSSI      int      `json:"rssi"`
+	Timestamp UnixTime `json:"timestamp"`
+	// Data is the raw advertisement as hex
+	Data string `json:"data"`
+}
+
+// UnixTime is a timestamp in seconds since the Unix epoch. Gateway firmware versions send timestamps
+// either as numbers or as strings.
+type UnixTime struct {
+	time.Time
+}
+
+func (t *UnixTime) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var secs int64
+	switch v := v.(type) {
+	case float64:
+		secs = int64(v)
+	case string:
+		if v == "" {
+			t.Time = time.Time{}
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q: %w", v, err)
+		}
+		secs = n
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	default:
+		return fmt.Errorf("invalid timestamp %v", v)
+	}
+	t.Time = time.Unix(secs, 0).UTC()
+	return nil
+}
+
+// ManufacturerData returns the manufacturer specific data of a raw BLE advertisement
+func ManufacturerData(adv []byte) ([]byte, error) {
+	for i := 0; i < len(adv); {
+		length := int(adv[i])
+		if length == 0 {
+			break
+		}
+		if i+1+length > len(adv) {
+			return nil, fmt.Errorf("advertisement structure at offset %d exceeds advertisement length", i)
+		}
+		if adv[i+1] == manufacturerSpecificData {
+			return adv[i+2 : i+1+length], nil
+		}
+		i += 1 + length
+	}
+	return nil, ErrNoManufacturerData
+}
+
+// Advertisements returns the advertisements of the tags in the payload
+func (p Payload) Advertisements() ([]Advertisement, error) {
+	var advs []Advertisement
+	var errs []error
+	for addr, tag := range p.Data.Tags {
+		raw, err := hex.DecodeString(tag.Data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid data for tag %s: %w", addr, err))
+			continue
+		}
+		data, err := ManufacturerData(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid data for tag %s: %w", addr, err))
+			continue
+		}
+		ts := tag.Timestamp.Time
+		if ts.IsZero() {
+			ts = p.Data.Timestamp.Time
+		}
+		advs = append(advs, Advertisement{
+			addr:             ble.NewAddr(addr),
+			manufacturerData: data,
+			rssi:             tag.RSSI,
+			gateway:          p.Data.GatewayMAC,
+			timestamp:        ts,
+		})
+	}
+	return advs, errors.Join(errs...)
+}
+
+// Advertisement is an advertisement relayed by a gateway
+type Advertisement struct {
+	addr             ble.Addr
+	manufacturerData []byte
+	rssi             int
+	gateway          string
+	timestamp        time.Time
+}
+
+// NewAdvertisement creates an advertisement of the given tag relayed by the gateway
+func NewAdvertisement(addr string, manufacturerData []byte, rssi int, gateway string, timestamp time.Time) Advertisement {
+	return Advertisement{
+		addr:             ble.NewAddr(addr),
+		manufacturerData: manufacturerData,
+		rssi:             rssi,
+		gateway:          gateway,
+		timestamp:        timestamp,
+	}
+}
+
+func (a Advertisement) LocalName() string {
+	return ""
+}
+
+func (a Advertisement) ManufacturerData() []byte {
+	return a.manufacturerData
+}
+
+func (a Advertisement) ServiceData() []ble.ServiceData {
+	return nil
+}
+
+func (a Advertisement) Services() []ble.UUID {
+	return nil
+}
+
+func (a Advertisement) OverflowService() []ble.UUID {
+	return nil
+}
+
+func (a Advertisement) TxPowerLevel() int {
+	return 0
+}
+
+func (a Advertisement) Connectable() bool {
+	return false
+}
+
+func (a Advertisement) SolicitedService() []ble.UUID {
+	return nil
+}
+
+func (a Advertisement) RSSI() int {
+	return a.rssi
+}
+
+func (a Advertisement) Addr() ble.Addr {
+	return a.addr
+}
+
+// Adapter returns the MAC address of the gateway that received the advertisement
+func (a Advertisement) Adapter() string {
+	return a.gateway
+}
+
+// Timestamp returns the time the gateway received the advertisement
+func (a Advertisement) Timestamp() time.Time {
+	return a.timestamp
+}
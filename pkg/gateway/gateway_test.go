@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+const testPayload = `{
+  "data": {
+    "coordinates": "",
+    "timestamp": "1636457416",
+    "gw_mac": "C8:25:2D:8E:9C:2C",
+    "tags": {
+      "F4:1F:0C:28:CB:D6": {
+        "rssi": -51,
+        "timestamp": 1636457415,
+        "data": "0201061BFF99040512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"
+      }
+    }
+  }
+}`
+
+func TestPayloadAdvertisements(t *testing.T) {
+	var payload Payload
+	require.NoError(t, json.Unmarshal([]byte(testPayload), &payload))
+	assert.Equal(t, "C8:25:2D:8E:9C:2C", payload.Data.GatewayMAC)
+	assert.Equal(t, time.Unix(1636457416, 0).UTC(), payload.Data.Timestamp.Time)
+	advs, err := payload.Advertisements()
+	require.NoError(t, err)
+	require.Len(t, advs, 1)
+	a := advs[0]
+	assert.Equal(t, "f4:1f:0c:28:cb:d6", a.Addr().String())
+	assert.Equal(t, -51, a.RSSI())
+	assert.Equal(t, "C8:25:2D:8E:9C:2C", a.Adapter())
+	assert.Equal(t, time.Unix(1636457415, 0).UTC(), a.Timestamp())
+	sd, err := sensor.Parse(a.ManufacturerData())
+	require.NoError(t, err)
+	assert.InDelta(t, 24.3, *sd.Temperature, 0.001)
+	assert.InDelta(t, 53.49, *sd.Humidity, 0.001)
+}
+
+func TestPayloadInvalidTagData(t *testing.T) {
+	payload := Payload{Data: PayloadData{Tags: map[string]Tag{
+		"F4:1F:0C:28:CB:D6": {Data: "0201061BFF99040512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"},
+		"CC:CA:7E:52:CC:34": {Data: "not hex"},
+		"FB:E1:B7:04:95:EE": {Data: "020106"},
+	}}}
+	advs, err := payload.Advertisements()
+	assert.Len(t, advs, 1, "valid tags are returned despite invalid ones")
+	assert.ErrorContains(t, err, "CC:CA:7E:52:CC:34")
+	assert.ErrorIs(t, err, ErrNoManufacturerData)
+}
+
+func TestManufacturerData(t *testing.T) {
+	raw, _ := hex.DecodeString("0201060303AAFE05FF990403FF")
+	data, err := ManufacturerData(raw)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x99, 0x04, 0x03, 0xFF}, data)
+	_, err = ManufacturerData([]byte{0x02, 0x01, 0x06, 0x1B, 0xFF, 0x99})
+	assert.ErrorContains(t, err, "exceeds advertisement length")
+	_, err = ManufacturerData(nil)
+	assert.ErrorIs(t, err, ErrNoManufacturerData)
+}
+
+func TestUnixTime(t *testing.T) {
+	var ts UnixTime
+	require.NoError(t, json.Unmarshal([]byte(`"1636457416"`), &ts))
+	assert.Equal(t, int64(1636457416), ts.Unix())
+	require.NoError(t, json.Unmarshal([]byte(`1636457416`), &ts))
+	assert.Equal(t, int64(1636457416), ts.Unix())
+	require.NoError(t, json.Unmarshal([]byte(`""`), &ts))
+	assert.True(t, ts.IsZero())
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &ts))
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DefaultTopic is the topic filter matching the messages Ruuvi Gateways publish with the default topic prefix
+const DefaultTopic = "ruuvi/#"
+
+// ErrNotTagTopic is returned for messages that are not published to the topic of a tag,
+// such as the status messages of gateways
+var ErrNotTagTopic = errors.New("topic does not end with a tag MAC address")
+
+// Message is the JSON document a Ruuvi Gateway publishes to MQTT for each advertisement
+type Message struct {
+	GatewayMAC       string   `json:"gw_mac"`
+	RSSI             int      `json:"rssi"`
+	GatewayTimestamp UnixTime `json:"gwts"`
+	Timestamp        UnixTime `json:"ts"`
+	// Data is the raw advertisement as hex
+	Data        string `json:"data"`
+	Coordinates string `json:"coords"`
+}
+
+// ParseMessage parses a message published by a gateway to a topic of the form <prefix>/<gw_mac>/<tag_mac>
+func ParseMessage(topic string, payload []byte) (Advertisement, error) {
+	levels := strings.Split(topic, "/")
+	tag := levels[len(levels)-1]
+	if _, err := net.ParseMAC(tag); err != nil {
+		return Advertisement{}, ErrNotTagTopic
+	}
+	var msg Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return Advertisement{}, fmt.Errorf("invalid message for tag %s: %w", tag, err)
+	}
+	raw, err := hex.DecodeString(msg.Data)
+	if err != nil {
+		return Advertisement{}, fmt.Errorf("invalid data for tag %s: %w", tag, err)
+	}
+	data, err := ManufacturerData(raw)
+	if err != nil {
+		return Advertisement{}, fmt.Errorf("invalid data for tag %s: %w", tag, err)
+	}
+	gw := msg.GatewayMAC
+	if gw == "" && len(levels) >= 2 {
+		gw = levels[len(levels)-2]
+	}
+	ts := msg.Timestamp.Time
+	if ts.IsZero() {
+		ts = msg.GatewayTimestamp.Time
+	}
+	return NewAdvertisement(tag, data, msg.RSSI, gw, ts), nil
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMessage = `{"gw_mac":"C8:25:2D:8E:9C:2C","rssi":-62,"aoa":[],"gwts":"1636457416","ts":"1636457415","data":"0201061BFF99040512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F","coords":""}`
+
+func TestParseMessage(t *testing.T) {
+	a, err := ParseMessage("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D6", []byte(testMessage))
+	require.NoError(t, err)
+	assert.Equal(t, "f4:1f:0c:28:cb:d6", a.Addr().String())
+	assert.Equal(t, -62, a.RSSI())
+	assert.Equal(t, "C8:25:2D:8E:9C:2C", a.Adapter())
+	assert.Equal(t, time.Unix(1636457415, 0).UTC(), a.Timestamp())
+	assert.Equal(t, []byte{0x99, 0x04, 0x05}, a.ManufacturerData()[:3])
+}
+
+func TestParseMessageGatewayFromTopic(t *testing.T) {
+	a, err := ParseMessage("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D6", []byte(`{"rssi":-62,"gwts":1636457416,"data":"1BFF99040512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "C8:25:2D:8E:9C:2C", a.Adapter())
+	assert.Equal(t, time.Unix(1636457416, 0).UTC(), a.Timestamp(), "gateway timestamp is used without tag timestamp")
+}
+
+func TestParseMessageInvalid(t *testing.T) {
+	_, err := ParseMessage("ruuvi/C8:25:2D:8E:9C:2C/gw_status", []byte(`{"state":"online"}`))
+	assert.ErrorIs(t, err, ErrNotTagTopic)
+	_, err = ParseMessage("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D6", []byte(`{`))
+	assert.ErrorContains(t, err, "invalid message")
+	_, err = ParseMessage("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D6", []byte(`{"data":"020106"}`))
+	assert.ErrorIs(t, err, ErrNoManufacturerData)
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// maxPayloadSize limits the size of accepted payloads
+const maxPayloadSize = 1 << 20
+
+// Receiver is an HTTP handler that accepts payloads posted by Ruuvi Gateways in the custom HTTP format.
+// Receiver is also a BLE scanner that delivers the received advertisements to the handlers of active scans.
+// Payloads received while no scan is active are dropped.
+type Receiver struct {
+	*relay
+	token string
+}
+
+// NewReceiver creates a receiver. If token is not empty, requests must carry it as a bearer token.
+func NewReceiver(logger *slog.Logger, token string) *Receiver {
+	return &Receiver{
+		relay: newRelay(logger),
+		token: token,
+	}
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var payload Payload
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxPayloadSize)).Decode(&payload); err != nil {
+		r.logger.LogAttrs(req.Context(), slog.LevelWarn, "Invalid gateway payload", slog.String("remote", req.RemoteAddr), slog.Any("error", err))
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	advs, err := payload.Advertisements()
+	if err != nil {
+		r.logger.LogAttrs(req.Context(), slog.LevelWarn, "Invalid tag data in gateway payload", slog.String("gateway", payload.Data.GatewayMAC), slog.Any("error", err))
+	}
+	r.logger.LogAttrs(req.Context(), slog.LevelDebug, "Received gateway payload", slog.String("gateway", payload.Data.GatewayMAC), slog.Int("tags", len(advs)))
+	r.deliver(req.Context(), advs...)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Receiver) authorized(req *http.Request) bool {
+	if r.token == "" {
+		return true
+	}
+	expected := "Bearer " + r.token
+	return subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte(expected)) == 1
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func startScan(t *testing.T, r interface {
+	Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error
+	activeScans() int
+}, f ble.AdvFilter) (chan ble.Advertisement, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan ble.Advertisement, 8)
+	go r.Scan(ctx, true, func(a ble.Advertisement) {
+		received <- a
+	}, f)
+	require.Eventually(t, func() bool {
+		return r.activeScans() == 1
+	}, 5*time.Second, time.Millisecond)
+	return received, cancel
+}
+
+func post(r *Receiver, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReceiver(t *testing.T) {
+	r := NewReceiver(logger, "")
+	received, cancel := startScan(t, r, nil)
+	defer cancel()
+	w := post(r, testPayload, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	a := <-received
+	assert.Equal(t, "f4:1f:0c:28:cb:d6", a.Addr().String())
+}
+
+func TestReceiverFilter(t *testing.T) {
+	r := NewReceiver(logger, "")
+	received, cancel := startScan(t, r, func(a ble.Advertisement) bool {
+		return false
+	})
+	defer cancel()
+	assert.Equal(t, http.StatusOK, post(r, testPayload, "").Code)
+	assert.Empty(t, received)
+}
+
+func TestReceiverToken(t *testing.T) {
+	r := NewReceiver(logger, "secret")
+	assert.Equal(t, http.StatusUnauthorized, post(r, testPayload, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, post(r, testPayload, "wrong").Code)
+	assert.Equal(t, http.StatusOK, post(r, testPayload, "secret").Code)
+}
+
+func TestReceiverInvalidRequests(t *testing.T) {
+	r := NewReceiver(logger, "")
+	assert.Equal(t, http.StatusBadRequest, post(r, "{", "").Code)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestReceiverScanEndsWithContext(t *testing.T) {
+	r := NewReceiver(logger, "")
+	_, cancel := startScan(t, r, nil)
+	cancel()
+	require.Eventually(t, func() bool {
+		return r.activeScans() == 0
+	}, 5*time.Second, time.Millisecond)
+}
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/go-ble/ble"
+)
+
+// relay delivers advertisements received from gateways to the handlers of active scans
+type relay struct {
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	scans map[*scan]struct{}
+}
+
+type scan struct {
+	ctx context.Context
+	h   ble.AdvHandler
+	f   ble.AdvFilter
+}
+
+func newRelay(logger *slog.Logger) *relay {
+	return &relay{
+		logger: logger,
+		scans:  make(map[*scan]struct{}),
+	}
+}
+
+// Scan delivers received advertisements to the handler until the context is done
+func (r *relay) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	s := &scan{ctx: ctx, h: h, f: f}
+	r.mu.Lock()
+	r.scans[s] = struct{}{}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.scans, s)
+		r.mu.Unlock()
+	}()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *relay) deliver(ctx context.Context, advs ...Advertisement) {
+	// Handlers may block until the measurements are read, so they are called without holding the lock to
+	// let scans start and end in the meantime
+	r.mu.RLock()
+	scans := make([]*scan, 0, len(r.scans))
+	for s := range r.scans {
+		scans = append(scans, s)
+	}
+	r.mu.RUnlock()
+	if len(scans) == 0 {
+		r.logger.LogAttrs(ctx, slog.LevelDebug, "Dropping gateway data because no scan is active")
+		return
+	}
+	for _, s := range scans {
+		for _, a := range advs {
+			if s.ctx.Err() != nil {
+				break
+			}
+			if s.f == nil || s.f(a) {
+				s.h(a)
+			}
+		}
+	}
+}
+
+func (r *relay) activeScans() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.scans)
+}
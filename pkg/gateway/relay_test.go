@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayScanEndsWhileDelivering(t *testing.T) {
+	r := newRelay(logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delivering := make(chan struct{})
+	release := make(chan struct{})
+	go r.Scan(ctx, true, func(a ble.Advertisement) {
+		close(delivering)
+		<-release
+	}, nil)
+	require.Eventually(t, func() bool {
+		return r.activeScans() == 1
+	}, 5*time.Second, time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		r.deliver(context.Background(), NewAdvertisement("f4:1f:0c:28:cb:d6", nil, -60, "gateway", time.Now()))
+		close(done)
+	}()
+	<-delivering
+	cancel()
+	require.Eventually(t, func() bool {
+		return r.activeScans() == 0
+	}, 5*time.Second, time.Millisecond, "the scan ends while its handler is blocked")
+	close(release)
+	<-done
+}
@@ -0,0 +1,105 @@
+//go:build mqtt
+
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Subscriber subscribes to the MQTT topics Ruuvi Gateways publish advertisements to. Subscriber is a
+// BLE scanner that delivers the received advertisements to the handlers of active scans. Messages received
+// while no scan is active are dropped.
+type Subscriber struct {
+	*relay
+	client mqtt.Client
+	topics []string
+	qos    byte
+}
+
+// NewSubscriber connects to the broker and subscribes to the configured topics. The topics are subscribed
+// to again whenever the connection is restored.
+func NewSubscriber(logger *slog.Logger, cfg SubscriberConfig) (*Subscriber, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("MQTT broker address must be specified")
+	}
+	s := &Subscriber{
+		relay:  newRelay(logger),
+		topics: cfg.Topics,
+		qos:    cfg.QoS,
+	}
+	if len(s.topics) == 0 {
+		s.topics = []string{DefaultTopic}
+	}
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(cfg.Addr)
+	opts.SetClientID(cfg.ClientId)
+	if cfg.Username != "" && cfg.Password != "" {
+		opts.SetUsername(cfg.Username)
+		opts.SetPassword(cfg.Password)
+	}
+	if cfg.CaFile != "" {
+		ca, err := os.ReadFile(cfg.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		certpool := x509.NewCertPool()
+		certpool.AppendCertsFromPEM(ca)
+		opts.SetTLSConfig(&tls.Config{RootCAs: certpool})
+	}
+	opts.SetAutoReconnect(true)
+	opts.SetOnConnectHandler(s.subscribe)
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		logger.LogAttrs(nil, slog.LevelWarn, "Lost connection to MQTT broker", slog.Any("error", err))
+	})
+	s.client = mqtt.NewClient(opts)
+	token := s.client.Connect()
+	if !token.WaitTimeout(30 * time.Second) {
+		return nil, fmt.Errorf("timed out connecting to MQTT broker %s", cfg.Addr)
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Subscriber) subscribe(client mqtt.Client) {
+	filters := make(map[string]byte, len(s.topics))
+	for _, topic := range s.topics {
+		filters[topic] = s.qos
+	}
+	token := client.SubscribeMultiple(filters, s.handle)
+	if token.Wait() && token.Error() != nil {
+		s.logger.LogAttrs(nil, slog.LevelError, "Failed to subscribe to MQTT topics", slog.Any("topics", s.topics), slog.Any("error", token.Error()))
+		return
+	}
+	s.logger.LogAttrs(nil, slog.LevelInfo, "Subscribed to MQTT topics", slog.Any("topics", s.topics))
+}
+
+func (s *Subscriber) handle(_ mqtt.Client, msg mqtt.Message) {
+	ctx := context.Background()
+	a, err := ParseMessage(msg.Topic(), msg.Payload())
+	if errors.Is(err, ErrNotTagTopic) {
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "Ignoring MQTT message", slog.String("topic", msg.Topic()))
+		return
+	}
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Invalid gateway MQTT message", slog.String("topic", msg.Topic()), slog.Any("error", err))
+		return
+	}
+	s.deliver(ctx, a)
+}
+
+// Close disconnects from the broker
+func (s *Subscriber) Close() error {
+	s.client.Disconnect(250)
+	return nil
+}
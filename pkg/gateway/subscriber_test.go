@@ -0,0 +1,173 @@
+//go:build mqtt
+
+package gateway
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBroker is a minimal in-process MQTT broker that supports QoS 0 subscriptions
+type testBroker struct {
+	t          *testing.T
+	ln         net.Listener
+	subscribed chan []string
+
+	mu            sync.Mutex
+	subscriptions map[net.Conn][]string
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &testBroker{
+		t:             t,
+		ln:            ln,
+		subscribed:    make(chan []string, 8),
+		subscriptions: make(map[net.Conn][]string),
+	}
+	go b.serve()
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	return b
+}
+
+func (b *testBroker) Addr() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func (b *testBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *testBroker) handle(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscriptions, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		var reply packets.ControlPacket
+		switch p := cp.(type) {
+		case *packets.ConnectPacket:
+			reply = packets.NewControlPacket(packets.Connack)
+		case *packets.SubscribePacket:
+			b.mu.Lock()
+			b.subscriptions[conn] = append(b.subscriptions[conn], p.Topics...)
+			b.mu.Unlock()
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = make([]byte, len(p.Topics))
+			reply = suback
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+		if reply != nil {
+			b.mu.Lock()
+			err := reply.Write(conn)
+			b.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+		if p, ok := cp.(*packets.SubscribePacket); ok {
+			b.subscribed <- p.Topics
+		}
+	}
+}
+
+// Publish sends the message to the clients subscribed to a matching topic filter
+func (b *testBroker) Publish(topic string, payload []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for conn, filters := range b.subscriptions {
+		for _, filter := range filters {
+			if topicMatches(filter, topic) {
+				pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+				pub.TopicName = topic
+				pub.Payload = payload
+				require.NoError(b.t, pub.Write(conn))
+				break
+			}
+		}
+	}
+}
+
+func topicMatches(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) || (f != "+" && f != tl[i]) {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+func TestSubscriber(t *testing.T) {
+	broker := newTestBroker(t)
+	sub, err := NewSubscriber(logger, SubscriberConfig{Addr: broker.Addr(), ClientId: "test"})
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, []string{DefaultTopic}, <-broker.subscribed)
+	received, cancel := startScan(t, sub, nil)
+	defer cancel()
+	broker.Publish("ruuvi/C8:25:2D:8E:9C:2C/gw_status", []byte(`{"state":"online"}`))
+	broker.Publish("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D6", []byte(testMessage))
+	a := <-received
+	assert.Equal(t, "f4:1f:0c:28:cb:d6", a.Addr().String())
+	assert.Equal(t, -62, a.RSSI())
+	assert.Equal(t, "C8:25:2D:8E:9C:2C", a.(Advertisement).Adapter())
+}
+
+func TestSubscriberTopics(t *testing.T) {
+	broker := newTestBroker(t)
+	topics := []string{"ruuvi/C8:25:2D:8E:9C:2C/+"}
+	sub, err := NewSubscriber(logger, SubscriberConfig{Addr: broker.Addr(), ClientId: "test", Topics: topics})
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, topics, <-broker.subscribed)
+	received, cancel := startScan(t, sub, func(a ble.Advertisement) bool {
+		return a.RSSI() > -70
+	})
+	defer cancel()
+	broker.Publish("ruuvi/AA:AA:AA:AA:AA:AA/F4:1F:0C:28:CB:D6", []byte(testMessage))
+	broker.Publish("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D6", []byte(strings.Replace(testMessage, "-62", "-90", 1)))
+	broker.Publish("ruuvi/C8:25:2D:8E:9C:2C/F4:1F:0C:28:CB:D7", []byte(testMessage))
+	// Messages are delivered in order, so the earlier messages have been dropped if the last one arrives first
+	a := <-received
+	assert.Equal(t, "f4:1f:0c:28:cb:d7", a.Addr().String(), "messages are filtered by topic and scan filter")
+}
+
+func TestSubscriberConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	_, err = NewSubscriber(logger, SubscriberConfig{Addr: "tcp://" + addr, ClientId: "test"})
+	assert.Error(t, err)
+}
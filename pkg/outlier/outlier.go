@@ -0,0 +1,279 @@
+package outlier
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// Method is a method for detecting outlying values of a field
+type Method string
+
+const (
+	// Median rejects values that deviate from the median of the previous values by more than the threshold
+	Median Method = "median"
+	// Hampel rejects values that deviate from the median of the previous values by more than the threshold
+	// times the scaled median absolute deviation of the previous values
+	Hampel Method = "hampel"
+	// Rate rejects values that have changed from the previous accepted value faster than the threshold
+	// per minute
+	Rate Method = "rate"
+)
+
+const (
+	// DefaultWindow is the default number of previous values the median and Hampel filters compare against
+	DefaultWindow = 5
+	// DefaultHampelThreshold is the default number of scaled median absolute deviations the Hampel filter
+	// accepts
+	DefaultHampelThreshold = 3.0
+)
+
+// madScale scales the median absolute deviation to estimate the standard deviation of normally
+// distributed values
+const madScale = 1.4826
+
+// Rule rejects outlying values of a single field
+type Rule struct {
+	// Field is the JSON name of a numeric field of the measurements, such as temperature
+	Field  string
+	Method Method
+	// Window is the number of previous values the median and Hampel filters compare against. Values are
+	// not rejected until the window is full.
+	Window int
+	// Threshold is the maximum deviation from the median for the median filter, the maximum number of
+	// scaled median absolute deviations for the Hampel filter and the maximum change per minute for the
+	// rate of change filter
+	Threshold float64
+	// Tolerance is the deviation from the median that the Hampel filter always accepts. It keeps small
+	// changes from being rejected when the previous values are all equal.
+	Tolerance float64
+}
+
+// Validate checks the rule and fills in the defaults of unset settings
+func (r *Rule) Validate() error {
+	if _, ok := fields[r.Field]; !ok {
+		return fmt.Errorf("unknown field: %s", r.Field)
+	}
+	if r.Window < 0 || r.Threshold < 0 || r.Tolerance < 0 {
+		return fmt.Errorf("%s filter for %s: settings must not be negative", r.Method, r.Field)
+	}
+	switch r.Method {
+	case Median, Hampel:
+		if r.Window == 0 {
+			r.Window = DefaultWindow
+		}
+		if r.Method == Hampel && r.Threshold == 0 {
+			r.Threshold = DefaultHampelThreshold
+		}
+	case Rate:
+	default:
+		return fmt.Errorf("unknown outlier filter method: %s", r.Method)
+	}
+	if r.Threshold == 0 {
+		return fmt.Errorf("%s filter for %s: threshold must be specified", r.Method, r.Field)
+	}
+	return nil
+}
+
+// Rejection describes why a measurement was rejected
+type Rejection struct {
+	Field  string
+	Method Method
+	Value  float64
+	// Reference is the median of the previous values or the previous accepted value the value was compared to
+	Reference float64
+	// Limit is the largest deviation from the reference that would have been accepted
+	Limit float64
+}
+
+func (r Rejection) String() string {
+	return fmt.Sprintf("%s %v deviates from %v by more than %v (%s)", r.Field, r.Value, r.Reference, r.Limit, r.Method)
+}
+
+// Filter rejects measurements that contain outlying values. Each tag is filtered with its own rules,
+// or the default rules if it has none, using the recent values of the tag.
+// Filter is safe for concurrent use.
+type Filter struct {
+	defaults []Rule
+	tags     map[string][]Rule
+	mu       sync.Mutex
+	states   map[stateKey]*state
+	rejected map[string]int
+}
+
+type stateKey struct {
+	addr string
+	rule int
+}
+
+type state struct {
+	window []float64
+	// last and lastTime are the previous accepted value and its timestamp
+	last     float64
+	lastTime time.Time
+	seen     bool
+}
+
+// New creates a filter that filters the tags in tags with their rules and other tags with the default rules.
+// The rules of a tag replace the default rules for the same fields.
+func New(defaults []Rule, tags map[string][]Rule) (*Filter, error) {
+	defaults = append([]Rule(nil), defaults...)
+	for i := range defaults {
+		if err := defaults[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	merged := make(map[string][]Rule)
+	for addr, rules := range tags {
+		rules = append([]Rule(nil), rules...)
+		overridden := make(map[string]bool)
+		for i := range rules {
+			if err := rules[i].Validate(); err != nil {
+				return nil, fmt.Errorf("RuuviTag %s: %w", addr, err)
+			}
+			overridden[rules[i].Field] = true
+		}
+		merged[addr] = rules
+		for _, r := range defaults {
+			if !overridden[r.Field] {
+				merged[addr] = append(merged[addr], r)
+			}
+		}
+	}
+	return &Filter{
+		defaults: defaults,
+		tags:     merged,
+		states:   make(map[stateKey]*state),
+		rejected: make(map[string]int),
+	}, nil
+}
+
+func (f *Filter) rules(addr string) []Rule {
+	if rules, ok := f.tags[addr]; ok {
+		return rules
+	}
+	return f.defaults
+}
+
+// Check records the values of the measurement and reports whether the measurement is rejected. A measurement
+// is rejected if any of its values is rejected by the rules of its tag.
+func (f *Filter) Check(m sensor.Data) (Rejection, bool) {
+	rules := f.rules(m.Addr)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var (
+		rejection Rejection
+		rejected  bool
+	)
+	for i, r := range rules {
+		v, ok := value(m, r.Field)
+		if !ok {
+			continue
+		}
+		key := stateKey{addr: m.Addr, rule: i}
+		s := f.states[key]
+		if s == nil {
+			s = new(state)
+			f.states[key] = s
+		}
+		if rej, ok := s.check(r, v, m.Timestamp); ok && !rejected {
+			rejection, rejected = rej, true
+		}
+	}
+	if rejected {
+		f.rejected[m.Addr]++
+	}
+	return rejection, rejected
+}
+
+// Rejected returns the number of rejected measurements of the tag
+func (f *Filter) Rejected(addr string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rejected[addr]
+}
+
+// check checks the value against the rule and records it
+func (s *state) check(r Rule, v float64, ts time.Time) (Rejection, bool) {
+	rejection := Rejection{Field: r.Field, Method: r.Method, Value: v}
+	rejected := false
+	switch r.Method {
+	case Median, Hampel:
+		if len(s.window) == r.Window {
+			med := median(s.window)
+			limit := r.Threshold
+			if r.Method == Hampel {
+				deviations := make([]float64, len(s.window))
+				for i, w := range s.window {
+					deviations[i] = math.Abs(w - med)
+				}
+				limit = math.Max(r.Threshold*madScale*median(deviations), r.Tolerance)
+			}
+			rejection.Reference, rejection.Limit = med, limit
+			rejected = math.Abs(v-med) > limit
+		}
+		// Rejected values are kept in the window so that a lasting change is eventually accepted
+		s.window = append(s.window, v)
+		if len(s.window) > r.Window {
+			s.window = s.window[1:]
+		}
+	case Rate:
+		if s.seen {
+			// Measurements broadcast within the same second are compared as if a second had passed
+			minutes := math.Max(ts.Sub(s.lastTime).Minutes(), 1.0/60)
+			rejection.Reference, rejection.Limit = s.last, r.Threshold*minutes
+			rejected = math.Abs(v-s.last) > rejection.Limit
+		}
+		if !rejected {
+			s.last, s.lastTime, s.seen = v, ts, true
+		}
+	}
+	return rejection, rejected
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// fields maps the JSON names of the numeric fields of the measurements to their field indices
+var fields = numericFields()
+
+func numericFields() map[string]int {
+	m := make(map[string]int)
+	t := reflect.TypeOf(sensor.Data{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.Pointer {
+			continue
+		}
+		if k := f.Type.Elem().Kind(); k != reflect.Float64 && k != reflect.Int {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		m[name] = i
+	}
+	return m
+}
+
+func value(m sensor.Data, field string) (float64, bool) {
+	v := reflect.ValueOf(m).Field(fields[field])
+	if v.IsNil() {
+		return 0, false
+	}
+	if v.Elem().Kind() == reflect.Int {
+		return float64(v.Elem().Int()), true
+	}
+	return v.Elem().Float(), true
+}
@@ -0,0 +1,112 @@
+package outlier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+const (
+	addr1 = "cc:ca:7e:52:cc:34"
+	addr2 = "fb:e1:b7:04:95:ee"
+)
+
+var ts = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func temperature(addr string, t float64, at time.Duration) sensor.Data {
+	return sensor.Data{Addr: addr, Temperature: sensor.Float64(t), Timestamp: ts.Add(at)}
+}
+
+// feed checks the temperatures a second apart and returns the indices of the rejected ones
+func feed(f *Filter, addr string, temperatures ...float64) []int {
+	var rejected []int
+	for i, t := range temperatures {
+		if _, ok := f.Check(temperature(addr, t, time.Duration(i)*time.Second)); ok {
+			rejected = append(rejected, i)
+		}
+	}
+	return rejected
+}
+
+func TestMedian(t *testing.T) {
+	f, err := New([]Rule{{Field: "temperature", Method: Median, Window: 3, Threshold: 5}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 6}, feed(f, addr1, 20, 84, 21, 21, 84, 22, -40, 22))
+	assert.Equal(t, 2, f.Rejected(addr1))
+	assert.Zero(t, f.Rejected(addr2))
+}
+
+func TestMedianAcceptsLastingChange(t *testing.T) {
+	f, err := New([]Rule{{Field: "temperature", Method: Median, Window: 3, Threshold: 5}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 4}, feed(f, addr1, 20, 20, 20, 60, 60, 60, 61))
+}
+
+func TestHampel(t *testing.T) {
+	f, err := New([]Rule{{Field: "temperature", Method: Hampel, Tolerance: 0.5}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 8}, feed(f, addr1, 20, 20.4, 20, 20.2, 20.1, 30, 20.3, 20.1, 19))
+}
+
+func TestHampelRejection(t *testing.T) {
+	f, err := New([]Rule{{Field: "temperature", Method: Hampel, Window: 3, Threshold: 2}}, nil)
+	require.NoError(t, err)
+	feed(f, addr1, 20, 21, 22)
+	r, ok := f.Check(temperature(addr1, 30, 3*time.Second))
+	require.True(t, ok)
+	assert.Equal(t, Rejection{Field: "temperature", Method: Hampel, Value: 30, Reference: 21, Limit: 2 * madScale}, r)
+}
+
+func TestRate(t *testing.T) {
+	f, err := New([]Rule{{Field: "humidity", Method: Rate, Threshold: 6}}, nil)
+	require.NoError(t, err)
+	humidity := func(h float64, at time.Duration) bool {
+		_, ok := f.Check(sensor.Data{Addr: addr1, Humidity: sensor.Float64(h), Timestamp: ts.Add(at)})
+		return ok
+	}
+	assert.False(t, humidity(40, 0))
+	assert.False(t, humidity(40.05, time.Second))
+	assert.True(t, humidity(80, 2*time.Second), "changed 40 %% within a second")
+	assert.False(t, humidity(40.5, 10*time.Second), "compared to the previous accepted value")
+	assert.False(t, humidity(80, 10*time.Minute), "slow changes are accepted")
+	_, ok := f.Check(temperature(addr1, 84, 11*time.Minute))
+	assert.False(t, ok, "missing fields are ignored")
+}
+
+func TestTagRules(t *testing.T) {
+	defaults := []Rule{
+		{Field: "temperature", Method: Median, Window: 3, Threshold: 5},
+		{Field: "humidity", Method: Rate, Threshold: 6},
+	}
+	f, err := New(defaults, map[string][]Rule{addr2: {{Field: "temperature", Method: Median, Window: 3, Threshold: 50}}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, feed(f, addr1, 20, 20, 20, 60))
+	assert.Empty(t, feed(f, addr2, 20, 20, 20, 60), "tag rules override the defaults for the same field")
+	_, ok := f.Check(sensor.Data{Addr: addr2, Humidity: sensor.Float64(40), Timestamp: ts})
+	assert.False(t, ok)
+	_, ok = f.Check(sensor.Data{Addr: addr2, Humidity: sensor.Float64(80), Timestamp: ts.Add(time.Second)})
+	assert.True(t, ok, "default rules for other fields still apply")
+}
+
+func TestValidate(t *testing.T) {
+	r := Rule{Field: "temperature", Method: Hampel}
+	require.NoError(t, r.Validate())
+	assert.Equal(t, DefaultWindow, r.Window)
+	assert.Equal(t, DefaultHampelThreshold, r.Threshold)
+
+	for _, r := range []Rule{
+		{Field: "name", Method: Rate, Threshold: 1},
+		{Field: "temperature", Method: "mean", Threshold: 1},
+		{Field: "temperature", Method: Median},
+		{Field: "temperature", Method: Rate},
+		{Field: "temperature", Method: Median, Window: -1, Threshold: 1},
+	} {
+		assert.Error(t, r.Validate(), "%+v", r)
+	}
+	_, err := New(nil, map[string][]Rule{addr1: {{Field: "co2", Method: Rate}}})
+	assert.Error(t, err)
+}
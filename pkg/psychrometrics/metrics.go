@@ -0,0 +1,93 @@
+package psychrometrics
+
+import (
+	"fmt"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// Metric is a derived metric that can be calculated from sensor data
+type Metric string
+
+const (
+	MetricAbsoluteHumidity     Metric = "absolute_humidity"
+	MetricVaporPressureDeficit Metric = "vapor_pressure_deficit"
+	MetricHumidityRatio        Metric = "humidity_ratio"
+	MetricWetBulb              Metric = "wet_bulb"
+	MetricFrostPoint           Metric = "frost_point"
+	MetricHeatIndex            Metric = "heat_index"
+	MetricAirDensity           Metric = "air_density"
+)
+
+// AllMetrics contains every supported derived metric
+var AllMetrics = []Metric{
+	MetricAbsoluteHumidity,
+	MetricVaporPressureDeficit,
+	MetricHumidityRatio,
+	MetricWetBulb,
+	MetricFrostPoint,
+	MetricHeatIndex,
+	MetricAirDensity,
+}
+
+// ParseMetrics parses metric names. The name "all" selects every metric.
+func ParseMetrics(names []string) ([]Metric, error) {
+	var metrics []Metric
+	for _, name := range names {
+		if name == "all" {
+			return AllMetrics, nil
+		}
+		m := Metric(name)
+		if !isSupported(m) {
+			return nil, fmt.Errorf("unknown metric: %s", name)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// Calculate calculates the given metrics into sd. Metrics whose inputs are missing are left empty.
+func Calculate(sd *sensor.Data, metrics []Metric) {
+	if sd.Temperature == nil || sd.Humidity == nil {
+		return
+	}
+	t := *sd.Temperature
+	rh := *sd.Humidity
+	for _, m := range metrics {
+		switch m {
+		case MetricAbsoluteHumidity:
+			sd.AbsoluteHumidity = sensor.Float64(AbsoluteHumidity(t, rh))
+		case MetricVaporPressureDeficit:
+			sd.VaporPressureDeficit = sensor.Float64(VaporPressureDeficit(t, rh))
+		case MetricHumidityRatio:
+			if sd.Pressure != nil {
+				sd.HumidityRatio = sensor.Float64(HumidityRatio(t, rh, *sd.Pressure))
+			}
+		case MetricWetBulb:
+			if sd.Pressure != nil {
+				if wb, err := WetBulb(t, rh, *sd.Pressure); err == nil {
+					sd.WetBulb = sensor.Float64(wb)
+				}
+			}
+		case MetricFrostPoint:
+			if fp, err := FrostPoint(t, rh); err == nil {
+				sd.FrostPoint = sensor.Float64(fp)
+			}
+		case MetricHeatIndex:
+			sd.HeatIndex = sensor.Float64(HeatIndex(t, rh))
+		case MetricAirDensity:
+			if sd.Pressure != nil {
+				sd.AirDensity = sensor.Float64(AirDensity(t, rh, *sd.Pressure))
+			}
+		}
+	}
+}
+
+func isSupported(m Metric) bool {
+	for _, s := range AllMetrics {
+		if m == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package psychrometrics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/dewpoint"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/temperature"
+)
+
+// Physical constants
+const (
+	DryAirGasConstant  = 287.058  // J/(kg·K)
+	WaterVaporConstant = 461.495  // J/(kg·K)
+	MolarMassRatio     = 0.621945 // Ratio of molar masses of water vapor and dry air
+	PsychrometerCoeff  = 6.62e-4  // 1/K, ventilated psychrometer
+)
+
+// Below this temperature (Fahrenheit) the simple heat index formula is used
+const heatIndexThreshold = 80.0
+
+// VaporPressure returns the partial pressure of water vapor in pascals at the given temperature (Celsius)
+// and relative humidity (percent)
+func VaporPressure(temp, humidity float64) float64 {
+	return humidity / 100.0 * dewpoint.SaturationVaporPressure(temperature.Convert(temp, temperature.Celsius, temperature.Kelvin))
+}
+
+// AbsoluteHumidity returns the mass of water vapor in grams per cubic meter of air
+func AbsoluteHumidity(temp, humidity float64) float64 {
+	tempInK := temperature.Convert(temp, temperature.Celsius, temperature.Kelvin)
+	return VaporPressure(temp, humidity) / (WaterVaporConstant * tempInK) * 1000.0
+}
+
+// VaporPressureDeficit returns the difference between saturation and actual vapor pressure in kilopascals
+func VaporPressureDeficit(temp, humidity float64) float64 {
+	pvs := dewpoint.SaturationVaporPressure(temperature.Convert(temp, temperature.Celsius, temperature.Kelvin))
+	return (pvs - VaporPressure(temp, humidity)) / 1000.0
+}
+
+// HumidityRatio returns the mass of water vapor per mass of dry air in grams per kilogram.
+// Pressure is given in hectopascals.
+func HumidityRatio(temp, humidity, pressure float64) float64 {
+	e := VaporPressure(temp, humidity)
+	return MolarMassRatio * e / (pressure*100.0 - e) * 1000.0
+}
+
+// WetBulb returns the psychrometric wet-bulb temperature in Celsius. Pressure is given in hectopascals.
+func WetBulb(temp, humidity, pressure float64) (float64, error) {
+	tempInK := temperature.Convert(temp, temperature.Celsius, temperature.Kelvin)
+	p := pressure * 100.0
+	f := func(wbInK float64) float64 {
+		return dewpoint.SaturationVaporPressure(wbInK) - PsychrometerCoeff*p*(tempInK-wbInK)
+	}
+	wbInK, err := dewpoint.Solve(f, VaporPressure(temp, humidity), tempInK)
+	if err != nil {
+		return 0, err
+	}
+	return temperature.Convert(wbInK, temperature.Kelvin, temperature.Celsius), nil
+}
+
+// FrostPoint returns the temperature in Celsius at which the water vapor in the air would saturate over ice
+func FrostPoint(temp, humidity float64) (float64, error) {
+	tempInK := temperature.Convert(temp, temperature.Celsius, temperature.Kelvin)
+	if tempInK < dewpoint.MinTemperature || tempInK > dewpoint.MaxTemperature {
+		return 0, fmt.Errorf("temperature %f out of range", temp)
+	}
+	fpInK, err := dewpoint.Solve(dewpoint.SaturationVaporPressureIce, VaporPressure(temp, humidity), tempInK)
+	if err != nil {
+		return 0, err
+	}
+	return temperature.Convert(fpInK, temperature.Kelvin, temperature.Celsius), nil
+}
+
+// HeatIndex returns the apparent temperature in Celsius using the NOAA heat index equation
+func HeatIndex(temp, humidity float64) float64 {
+	t := temperature.Convert(temp, temperature.Celsius, temperature.Fahrenheit)
+	rh := humidity
+	hi := 0.5 * (t + 61.0 + (t-68.0)*1.2 + rh*0.094)
+	if (hi+t)/2 >= heatIndexThreshold {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh - 0.00683783*t*t -
+			0.05481717*rh*rh + 0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+		if rh < 13 && t >= 80 && t <= 112 {
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		} else if rh > 85 && t >= 80 && t <= 87 {
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+	return temperature.Convert(hi, temperature.Fahrenheit, temperature.Celsius)
+}
+
+// AirDensity returns the density of moist air in kilograms per cubic meter. Pressure is given in hectopascals.
+func AirDensity(temp, humidity, pressure float64) float64 {
+	tempInK := temperature.Convert(temp, temperature.Celsius, temperature.Kelvin)
+	e := VaporPressure(temp, humidity)
+	pd := pressure*100.0 - e
+	return pd/(DryAirGasConstant*tempInK) + e/(WaterVaporConstant*tempInK)
+}
@@ -0,0 +1,74 @@
+package psychrometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func TestAbsoluteHumidity(t *testing.T) {
+	assert.InDelta(t, 8.6, AbsoluteHumidity(20, 50), 0.1)
+	assert.InDelta(t, 18.2, AbsoluteHumidity(30, 60), 0.1)
+}
+
+func TestVaporPressureDeficit(t *testing.T) {
+	assert.InDelta(t, 1.27, VaporPressureDeficit(25, 60), 0.01)
+	assert.InDelta(t, 0.0, VaporPressureDeficit(25, 100), 0.001)
+}
+
+func TestHumidityRatio(t *testing.T) {
+	assert.InDelta(t, 7.3, HumidityRatio(20, 50, 1013.25), 0.1)
+}
+
+func TestWetBulb(t *testing.T) {
+	wb, err := WetBulb(20, 50, 1013.25)
+	require.NoError(t, err)
+	assert.InDelta(t, 13.7, wb, 0.2)
+
+	wb, err = WetBulb(30, 100, 1013.25)
+	require.NoError(t, err)
+	assert.InDelta(t, 30.0, wb, 0.1)
+}
+
+func TestFrostPoint(t *testing.T) {
+	fp, err := FrostPoint(-5, 80)
+	require.NoError(t, err)
+	assert.InDelta(t, -7.6, fp, 0.1)
+}
+
+func TestHeatIndex(t *testing.T) {
+	assert.InDelta(t, 40.9, HeatIndex(32, 70), 0.5)
+	assert.InDelta(t, 19.4, HeatIndex(20, 50), 0.1)
+}
+
+func TestAirDensity(t *testing.T) {
+	assert.InDelta(t, 1.204, AirDensity(20, 0, 1013.25), 0.001)
+	assert.InDelta(t, 1.199, AirDensity(20, 50, 1013.25), 0.001)
+}
+
+func TestParseMetrics(t *testing.T) {
+	metrics, err := ParseMetrics([]string{"absolute_humidity", "wet_bulb"})
+	require.NoError(t, err)
+	assert.Equal(t, []Metric{MetricAbsoluteHumidity, MetricWetBulb}, metrics)
+	metrics, err = ParseMetrics([]string{"all"})
+	require.NoError(t, err)
+	assert.Equal(t, AllMetrics, metrics)
+	_, err = ParseMetrics([]string{"humidex"})
+	assert.Error(t, err)
+}
+
+func TestCalculate(t *testing.T) {
+	sd := sensor.Data{
+		Temperature: sensor.Float64(20),
+		Humidity:    sensor.Float64(50),
+	}
+	Calculate(&sd, AllMetrics)
+	require.NotNil(t, sd.AbsoluteHumidity)
+	assert.InDelta(t, 8.6, *sd.AbsoluteHumidity, 0.1)
+	assert.NotNil(t, sd.HeatIndex)
+	assert.Nil(t, sd.WetBulb, "wet bulb requires pressure")
+	assert.Nil(t, sd.AirDensity, "air density requires pressure")
+}
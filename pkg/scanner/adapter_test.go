@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func TestMeasurementsMultipleAdapters(t *testing.T) {
+	advertisement2 := mockAdvertisement{addr: testAddr2, manufacturerData: testData}
+	meas := &Measurements{
+		Adapters: []Adapter{
+			{Name: "hci0", BLE: repeatingBLEScanner{advertisement: testAdvertisement, times: 3}},
+			{Name: "hci1", BLE: repeatingBLEScanner{advertisement: advertisement2, times: 2}},
+			{Name: "hci2", BLE: repeatingBLEScanner{advertisement: testAdvertisement, times: 2}},
+		},
+		Peripherals: map[string]string{testAddr1: "Backyard", testAddr2: "Upstairs"},
+		Dedup:       NewDeduplicator(time.Minute),
+		Logger:      logger,
+	}
+	received := make(map[string][]sensor.Data)
+	for sd := range meas.Channel(context.Background()) {
+		received[sd.Addr] = append(received[sd.Addr], sd)
+	}
+	require.Len(t, received[testAddr1], 1)
+	assert.Contains(t, []string{"hci0", "hci2"}, received[testAddr1][0].Adapter)
+	require.Len(t, received[testAddr2], 1)
+	assert.Equal(t, "hci1", received[testAddr2][0].Adapter)
+	assert.Equal(t, "Upstairs", received[testAddr2][0].Name)
+}
+
+func TestMeasurementsAdapterFailureStopsOtherAdapters(t *testing.T) {
+	blocking := blockingBLEScanner{started: make(chan struct{}, 1)}
+	meas := &Measurements{
+		Adapters: []Adapter{
+			{Name: "hci0", BLE: blocking},
+			{Name: "hci1", BLE: &failingBLEScanner{failures: 1}},
+		},
+		Peripherals: peripherals,
+		Logger:      logger,
+	}
+	ch, errs := meas.ChannelWithErrors(context.Background())
+	for range ch {
+	}
+	<-blocking.started
+	assert.ErrorIs(t, <-errs, errAdapter)
+}
+
+func TestInitMultipleDevices(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	dev := &countingDeviceCreator{}
+	e.dev = dev
+	require.NoError(t, e.Init("hci0", "hci1"))
+	assert.Equal(t, 2, dev.Created())
+	require.Len(t, e.meas.Adapters, 2)
+	assert.Equal(t, "hci0", e.meas.Adapters[0].Name)
+	assert.Equal(t, "hci1", e.meas.Adapters[1].Name)
+	assert.NotNil(t, e.meas.Dedup, "deduplication is required for merging adapters")
+}
+
+func TestDeviceID(t *testing.T) {
+	for name, want := range map[string]int{"default": -1, "hci0": 0, "hci1": 1, "hci12": 12} {
+		id, err := deviceID(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, id, name)
+	}
+	for _, name := range []string{"", "hci", "hci-1", "hcix", "usb0", "1"} {
+		_, err := deviceID(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestInitMultipleDevicesFailure(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	e.dev = &countingDeviceCreator{failures: 1}
+	assert.ErrorIs(t, e.Init("hci0", "hci1"), errAdapter)
+	assert.Empty(t, e.devices)
+}
+
+func TestSetAdapters(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	exp := new(mockExporter)
+	e.Exporters = []exporter.Exporter{exp}
+	e.SetAdapters(Adapter{Name: "http", BLE: replayOf(testAdvertisement)})
+	require.NoError(t, e.Init("default"))
+	assert.Empty(t, e.devices, "no devices are opened for custom adapters")
+	e.Collect(context.Background())
+	require.Len(t, exp.events, 1)
+	assert.Equal(t, "http", exp.events[0].Adapter)
+}
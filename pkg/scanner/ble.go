@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-ble/ble"
 )
@@ -10,9 +11,35 @@ type BLEScanner interface {
 	Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error
 }
 
+// defaultBLEScanner scans with the device returned by the device function at the start of each scan
 type defaultBLEScanner struct {
+	device func() ble.Device
 }
 
 func (s defaultBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
-	return ble.Scan(ctx, allowDup, h, f)
+	d := s.device()
+	if d == nil {
+		return errors.New("no Bluetooth device has been initialized")
+	}
+	return deviceScanner{device: d}.Scan(ctx, allowDup, h, f)
+}
+
+// deviceScanner scans with the given device instead of the default device
+type deviceScanner struct {
+	device ble.Device
+}
+
+func (s deviceScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	return s.device.Scan(ctx, allowDup, func(a ble.Advertisement) {
+		if f == nil || f(a) {
+			h(a)
+		}
+	})
+}
+
+// Adapter is a Bluetooth adapter used for scanning
+type Adapter struct {
+	// Name of the adapter, recorded in the measurements it receives
+	Name string
+	BLE  BLEScanner
 }
@@ -0,0 +1,19 @@
+package scanner
+
+import "time"
+
+// Clock tells the current time and waits for durations. It allows replacing the wall clock in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
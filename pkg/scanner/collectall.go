@@ -0,0 +1,138 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-ble/ble"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+const (
+	// DefaultNameTemplate names RuuviTags after the last four hex digits of their MAC address
+	DefaultNameTemplate = "RuuviTag {{.Suffix}}"
+	// DefaultQuietPeriod is how long a collection waits for new RuuviTags before finishing
+	DefaultQuietPeriod = 10 * time.Second
+)
+
+// CollectAll configures collecting measurements from all RuuviTags in range in addition to the configured
+// peripherals. RuuviTags that have not been configured are named with a template.
+type CollectAll struct {
+	// QuietPeriod is how long a collection continues after the last new RuuviTag was seen.
+	// Zero collects until the collection is cancelled.
+	QuietPeriod time.Duration
+
+	names *template.Template
+	allow []string
+	deny  []string
+}
+
+// TagName contains the values available in name templates
+type TagName struct {
+	// Addr is the MAC address in lower case with colons, e.g. cc:ca:7e:52:cc:34
+	Addr string
+	// MAC is the MAC address in upper case without colons, e.g. CCCA7E52CC34
+	MAC string
+	// Suffix is the last four hex digits of the MAC address, e.g. CC34
+	Suffix string
+}
+
+// NewCollectAll creates a configuration for collecting all RuuviTags. RuuviTags whose MAC address starts with
+// a prefix in deny are ignored. If allow is not empty, only RuuviTags whose MAC address starts with a prefix
+// in allow are collected. Prefixes may be written with or without colons.
+func NewCollectAll(nameTemplate string, allow, deny []string, quietPeriod time.Duration) (*CollectAll, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+	names, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	if err := names.Execute(new(strings.Builder), newTagName("00:00:00:00:00:00")); err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	return &CollectAll{
+		QuietPeriod: quietPeriod,
+		names:       names,
+		allow:       normalizePrefixes(allow),
+		deny:        normalizePrefixes(deny),
+	}, nil
+}
+
+// Accept reports whether measurements from the RuuviTag with the given address are collected
+func (c *CollectAll) Accept(addr string) bool {
+	mac := normalizeMAC(addr)
+	for _, prefix := range c.deny {
+		if strings.HasPrefix(mac, prefix) {
+			return false
+		}
+	}
+	if len(c.allow) == 0 {
+		return true
+	}
+	for _, prefix := range c.allow {
+		if strings.HasPrefix(mac, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the generated name of the RuuviTag with the given address
+func (c *CollectAll) Name(addr string) string {
+	var b strings.Builder
+	if err := c.names.Execute(&b, newTagName(addr)); err != nil {
+		return addr
+	}
+	return b.String()
+}
+
+// Filter returns a filter that accepts advertisements from the configured peripherals and from all other
+// RuuviTags accepted by the configuration
+func (c *CollectAll) Filter(peripherals map[string]string) ble.AdvFilter {
+	return func(a ble.Advertisement) bool {
+		if !sensor.IsRuuviTag(a.ManufacturerData()) {
+			return false
+		}
+		addr := a.Addr().String()
+		if _, ok := peripherals[addr]; ok {
+			return true
+		}
+		return c.Accept(addr)
+	}
+}
+
+// DefaultTagName returns the name the default name template generates for the address
+func DefaultTagName(addr string) string {
+	return "RuuviTag " + newTagName(addr).Suffix
+}
+
+func newTagName(addr string) TagName {
+	mac := strings.ToUpper(normalizeMAC(addr))
+	suffix := mac
+	if len(mac) > 4 {
+		suffix = mac[len(mac)-4:]
+	}
+	return TagName{
+		Addr:   strings.ToLower(addr),
+		MAC:    mac,
+		Suffix: suffix,
+	}
+}
+
+func normalizeMAC(addr string) string {
+	return strings.NewReplacer(":", "", "-", "").Replace(strings.ToLower(addr))
+}
+
+func normalizePrefixes(prefixes []string) []string {
+	var normalized []string
+	for _, p := range prefixes {
+		if p = normalizeMAC(strings.TrimSpace(p)); p != "" {
+			normalized = append(normalized, p)
+		}
+	}
+	return normalized
+}
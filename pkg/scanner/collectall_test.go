@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// lingeringBLEScanner delivers the advertisements and then scans until the context is done
+type lingeringBLEScanner struct {
+	advertisements []ble.Advertisement
+}
+
+func (m lingeringBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	for _, a := range m.advertisements {
+		if f == nil || f(a) {
+			h(a)
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func TestCollectAllAccept(t *testing.T) {
+	c, err := NewCollectAll("", []string{"CC:CA", "fbe1b7"}, []string{"fb-e1-b7-04"}, 0)
+	require.NoError(t, err)
+	assert.True(t, c.Accept(testAddr1))
+	assert.True(t, c.Accept("FB:E1:B7:05:00:00"))
+	assert.False(t, c.Accept(testAddr2), "deny takes precedence over allow")
+	assert.False(t, c.Accept(testAddr3))
+
+	c, err = NewCollectAll("", nil, nil, 0)
+	require.NoError(t, err)
+	assert.True(t, c.Accept(testAddr3))
+}
+
+func TestCollectAllName(t *testing.T) {
+	c, err := NewCollectAll("", nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "RuuviTag CC34", c.Name(testAddr1))
+	assert.Equal(t, DefaultTagName(testAddr1), c.Name(testAddr1))
+
+	c, err = NewCollectAll("{{.MAC}} ({{.Addr}})", nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "CCCA7E52CC34 (cc:ca:7e:52:cc:34)", c.Name("CC:CA:7E:52:CC:34"))
+}
+
+func TestCollectAllInvalidNameTemplate(t *testing.T) {
+	_, err := NewCollectAll("{{.Suffix", nil, nil, 0)
+	assert.Error(t, err)
+	_, err = NewCollectAll("{{.Serial}}", nil, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestCollectAllFilter(t *testing.T) {
+	c, err := NewCollectAll("", nil, []string{"fb"}, 0)
+	require.NoError(t, err)
+	f := c.Filter(map[string]string{testAddr2: "Upstairs"})
+	assert.True(t, f(testAdvertisement))
+	assert.True(t, f(mockAdvertisement{addr: testAddr2, manufacturerData: testData}), "configured peripherals are not denied")
+	assert.False(t, f(mockAdvertisement{addr: "fb:00:00:00:00:00", manufacturerData: testData}))
+	assert.False(t, f(mockAdvertisement{addr: testAddr3, manufacturerData: []byte{0x4c, 0x00, 0x02}}))
+}
+
+func TestScanOnceCollectAll(t *testing.T) {
+	scn := NewOnce(logger, nil)
+	exp := new(mockExporter)
+	scn.Exporters = []exporter.Exporter{exp}
+	source := replayOf(testAdvertisement, mockAdvertisement{addr: testAddr3, manufacturerData: testData})
+	source.Speed = 0
+	scn.SetBLEScanner(source)
+	c, err := NewCollectAll("Tag {{.Suffix}}", nil, nil, 0)
+	require.NoError(t, err)
+	scn.SetCollectAll(c)
+	require.NoError(t, scn.Init("default"))
+	require.NoError(t, scn.Scan(context.Background()))
+	require.Len(t, exp.events, 2)
+	assert.Equal(t, "Tag CC34", exp.events[0].Name)
+	assert.Equal(t, "Tag B8C5", exp.events[1].Name)
+}
+
+func TestCollectAllQuietPeriod(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	exp := chanExporter{ch: make(chan sensor.Data)}
+	e.Exporters = []exporter.Exporter{exp}
+	e.SetBLEScanner(lingeringBLEScanner{advertisements: []ble.Advertisement{
+		testAdvertisement,
+		mockAdvertisement{addr: testAddr2, manufacturerData: testData},
+	}})
+	clock := newFakeClock(cronTestStart)
+	e.clock = clock
+	c, err := NewCollectAll("", nil, nil, time.Minute)
+	require.NoError(t, err)
+	e.SetCollectAll(c)
+	require.NoError(t, e.Init("default"))
+	done := make(chan struct{})
+	go func() {
+		e.Collect(context.Background())
+		close(done)
+	}()
+	assert.Equal(t, "Test", (<-exp.ch).Name, "collection continues after all peripherals have been seen")
+	assert.Equal(t, "RuuviTag 95EE", (<-exp.ch).Name)
+	clock.Advance(30 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("collection finished before the quiet period")
+	default:
+	}
+	clock.Advance(30 * time.Second)
+	<-done
+}
@@ -2,27 +2,65 @@ package scanner
 
 import (
 	"context"
+	"encoding/hex"
 	"log/slog"
 	"time"
 
 	"github.com/go-ble/ble"
 
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/dewpoint"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/temperature"
 )
 
-// Read reads sensor data from advertisement
-func Read(a ble.Advertisement) (sd sensor.Data, err error) {
+// Read reads sensor data from advertisement. The key is used for decrypting encrypted data formats and may be nil.
+// The calibration is applied to the parsed readings before calculating dew point. Advertisements relayed by
+// other receivers, such as Ruuvi Gateways, may provide the time they were received and the receiving adapter.
+func Read(a ble.Advertisement, key []byte, cal calibration.Calibration) (sd sensor.Data, err error) {
 	addr := a.Addr().String()
 	data := a.ManufacturerData()
-	sd, err = sensor.Parse(data)
+	sd, err = sensor.ParseWithKey(data, key)
+	if err != nil {
+		return
+	}
+	cal.Apply(&sd)
 	sd.Addr = addr
 	sd.Timestamp = time.Now()
-	sd.DewPoint, _ = dewpoint.Calculate(sd.Temperature, temperature.Celsius, sd.Humidity)
+	if ts, ok := a.(interface{ Timestamp() time.Time }); ok && !ts.Timestamp().IsZero() {
+		sd.Timestamp = ts.Timestamp()
+	}
+	if ad, ok := a.(interface{ Adapter() string }); ok {
+		sd.Adapter = ad.Adapter()
+	}
+	sd.RSSI = sensor.Int(a.RSSI())
+	sd.RawData = hex.EncodeToString(data)
+	sd.AddressType = addressType(a)
+	sd.DewPoint = nil
+	if sd.Temperature != nil && sd.Humidity != nil {
+		if dp, err := dewpoint.Calculate(*sd.Temperature, temperature.Celsius, *sd.Humidity); err == nil {
+			sd.DewPoint = sensor.Float64(dp)
+		}
+	}
 	return
 }
 
+// addressType returns the address type of the advertisement if the BLE implementation exposes it
+func addressType(a ble.Advertisement) string {
+	at, ok := a.(interface{ AddressType() uint8 })
+	if !ok {
+		return ""
+	}
+	switch at.AddressType() {
+	case 0:
+		return "public"
+	case 1:
+		return "random"
+	default:
+		return "unknown"
+	}
+}
+
 // LogInvalidData logs invalid BLE advertisement data
 func LogInvalidData(ctx context.Context, logger *slog.Logger, data []byte, err error) {
 	var header []byte
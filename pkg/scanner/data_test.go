@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/gateway"
+)
+
+func TestRead(t *testing.T) {
+	sd, err := Read(testAdvertisement, nil, calibration.Calibration{})
+	require.NoError(t, err)
+	assert.Equal(t, testAddr1, sd.Addr)
+	require.NotNil(t, sd.DewPoint)
+	assert.InDelta(t, 44.7, *sd.DewPoint, 0.1)
+}
+
+func TestReadMissingTemperature(t *testing.T) {
+	data, err := hex.DecodeString("9904058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF")
+	require.NoError(t, err)
+	sd, err := Read(mockAdvertisement{addr: testAddr1, manufacturerData: data}, nil, calibration.Calibration{})
+	require.NoError(t, err)
+	assert.Nil(t, sd.Temperature)
+	assert.Nil(t, sd.DewPoint)
+}
+
+func TestReadCalibrated(t *testing.T) {
+	cal := calibration.Calibration{
+		Temperature: calibration.Linear{Offset: -5},
+		Humidity:    calibration.Linear{Offset: 10},
+		KeepRaw:     true,
+	}
+	sd, err := Read(testAdvertisement, nil, cal)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, *sd.Temperature)
+	assert.Equal(t, 70.0, *sd.Humidity)
+	assert.Equal(t, 55.0, *sd.RawTemperature)
+	assert.Equal(t, 60.0, *sd.RawHumidity)
+	require.NotNil(t, sd.DewPoint)
+	assert.InDelta(t, 43.0, *sd.DewPoint, 0.1)
+}
+
+func TestReadDiagnostics(t *testing.T) {
+	adv := testAdvertisement
+	adv.rssi = -72
+	sd, err := Read(adv, nil, calibration.Calibration{})
+	require.NoError(t, err)
+	require.NotNil(t, sd.RSSI)
+	assert.Equal(t, -72, *sd.RSSI)
+	assert.Equal(t, hex.EncodeToString(adv.manufacturerData), sd.RawData)
+	assert.Empty(t, sd.AddressType)
+}
+
+func TestReadRelayedAdvertisement(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	adv := gateway.NewAdvertisement(testAddr1, testData, -51, "C8:25:2D:8E:9C:2C", ts)
+	sd, err := Read(adv, nil, calibration.Calibration{})
+	require.NoError(t, err)
+	assert.Equal(t, ts, sd.Timestamp)
+	assert.Equal(t, "C8:25:2D:8E:9C:2C", sd.Adapter)
+	assert.Equal(t, -51, *sd.RSSI)
+}
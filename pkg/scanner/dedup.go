@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"encoding/hex"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+const (
+	// DefaultDedupWindow is the default time after which the state of a peripheral is forgotten
+	DefaultDedupWindow = 10 * time.Second
+
+	// maxSequenceNumber8 is the largest 8-bit measurement sequence number used by data format 6
+	maxSequenceNumber8 = 0xFF
+	// maxSequenceNumber16 is the largest valid 16-bit measurement sequence number. 0xFFFF indicates invalid.
+	maxSequenceNumber16 = 0xFFFE
+	// maxSequenceNumber24 is the largest valid 24-bit measurement sequence number used by extended formats.
+	maxSequenceNumber24 = 0xFFFFFE
+	// maxReorder is how many measurements a sequence number may lag behind the latest one and still be
+	// considered a late retransmission. Larger jumps backwards are considered a reboot of the peripheral.
+	maxReorder = 32
+)
+
+// Deduplicator drops measurements a RuuviTag broadcasts multiple times. Measurements are identified by their
+// sequence number. Measurements without a sequence number, such as those in data format 3, are considered
+// duplicates if their content equals the previous measurement of the same peripheral within the window.
+// The state of peripherals that have not been seen within the window is forgotten.
+// Deduplicator is safe for concurrent use.
+type Deduplicator struct {
+	window time.Duration
+	mu     sync.Mutex
+	latest map[string]sensor.Data
+	// pruned is the time the forgotten peripherals were last removed from latest
+	pruned time.Time
+}
+
+// NewDeduplicator creates a deduplicator that remembers the latest measurement of each peripheral for
+// the given window
+func NewDeduplicator(window time.Duration) *Deduplicator {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	return &Deduplicator{
+		window: window,
+		latest: make(map[string]sensor.Data),
+	}
+}
+
+// Duplicate reports whether the measurement has already been seen. Measurements that are not duplicates
+// are remembered as the latest measurement of the peripheral.
+func (d *Deduplicator) Duplicate(sd sensor.Data) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sd.Timestamp.Sub(d.pruned) >= d.window {
+		d.prune(sd.Timestamp)
+	}
+	prev, ok := d.latest[sd.Addr]
+	if ok && sd.Timestamp.Sub(prev.Timestamp) < d.window {
+		if sd.MeasurementNumber != nil && prev.MeasurementNumber != nil {
+			if isRetransmission(*prev.MeasurementNumber, *sd.MeasurementNumber, sequenceModulus(sd, *prev.MeasurementNumber)) {
+				return true
+			}
+		} else if sameContent(prev, sd) {
+			return true
+		}
+	}
+	d.latest[sd.Addr] = sd
+	return false
+}
+
+// prune forgets the peripherals that have not been seen within the window before now
+func (d *Deduplicator) prune(now time.Time) {
+	for addr, sd := range d.latest {
+		if now.Sub(sd.Timestamp) >= d.window {
+			delete(d.latest, addr)
+		}
+	}
+	d.pruned = now
+}
+
+// isRetransmission reports whether the sequence number is equal to or slightly behind the latest one.
+// The sequence number wraps around after its maximum value, so distances are calculated modulo the counter
+// range. A sequence number far behind the latest one means the peripheral was rebooted and started
+// counting from zero again.
+func isRetransmission(latest, seq, modulus int) bool {
+	behind := ((latest-seq)%modulus + modulus) % modulus
+	return behind <= maxReorder
+}
+
+// sequenceModulus returns the range of the measurement sequence number of the data format of the
+// measurement. The data format is read from the raw data. Without raw data, the range is guessed from
+// the sequence numbers.
+func sequenceModulus(sd sensor.Data, latest int) int {
+	if raw, err := hex.DecodeString(sd.RawData); err == nil && len(raw) > 2 {
+		switch raw[2] {
+		case 6:
+			return maxSequenceNumber8 + 1
+		case 5, 8:
+			return maxSequenceNumber16 + 1
+		case 0xE1:
+			return maxSequenceNumber24 + 1
+		}
+	}
+	if latest > maxSequenceNumber16 || *sd.MeasurementNumber > maxSequenceNumber16 {
+		return maxSequenceNumber24 + 1
+	}
+	return maxSequenceNumber16 + 1
+}
+
+// sameContent reports whether the measured values of the two measurements are equal
+func sameContent(a, b sensor.Data) bool {
+	return reflect.DeepEqual(measuredValues(a), measuredValues(b))
+}
+
+func measuredValues(sd sensor.Data) sensor.Data {
+	sd.Timestamp = time.Time{}
+	sd.RSSI = nil
+	sd.RawData = ""
+	sd.AddressType = ""
+	sd.Adapter = ""
+	return sd
+}
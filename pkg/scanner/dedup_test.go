@@ -0,0 +1,124 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+var dedupTestTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func sequenced(addr string, seq int, offset time.Duration) sensor.Data {
+	return sensor.Data{
+		Addr:              addr,
+		Temperature:       sensor.Float64(21.5),
+		MeasurementNumber: sensor.Int(seq),
+		Timestamp:         dedupTestTime.Add(offset),
+	}
+}
+
+func TestDeduplicateSequenceNumbers(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 100, 0)))
+	assert.True(t, d.Duplicate(sequenced(testAddr1, 100, time.Second)))
+	assert.False(t, d.Duplicate(sequenced(testAddr2, 100, time.Second)), "peripherals are deduplicated separately")
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 101, 2*time.Second)))
+	assert.True(t, d.Duplicate(sequenced(testAddr1, 100, 3*time.Second)), "late retransmission")
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 101, 20*time.Second)), "state is forgotten after the window")
+}
+
+func TestDeduplicateWraparound(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 65534, 0)))
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 0, time.Second)))
+	assert.True(t, d.Duplicate(sequenced(testAddr1, 65534, 2*time.Second)))
+	assert.True(t, d.Duplicate(sequenced(testAddr1, 0, 2*time.Second)))
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 1, 3*time.Second)))
+}
+
+func TestDeduplicateWraparound24Bit(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 0xFFFFFE, 0)))
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 0, time.Second)))
+	assert.True(t, d.Duplicate(sequenced(testAddr1, 0xFFFFFE, 2*time.Second)))
+}
+
+func TestDeduplicateWraparound8Bit(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	format6 := func(seq int, offset time.Duration) sensor.Data {
+		sd := sequenced(testAddr1, seq, offset)
+		sd.RawData = "990406"
+		return sd
+	}
+	assert.False(t, d.Duplicate(format6(255, 0)))
+	assert.False(t, d.Duplicate(format6(0, time.Second)))
+	assert.True(t, d.Duplicate(format6(255, 2*time.Second)), "late retransmission across the wrap")
+	assert.False(t, d.Duplicate(format6(1, 3*time.Second)))
+}
+
+func TestDeduplicatePrune(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	d.Duplicate(sequenced(testAddr1, 1, 0))
+	d.Duplicate(sequenced(testAddr2, 1, 5*time.Second))
+	assert.Len(t, d.latest, 2)
+	d.Duplicate(sequenced(testAddr3, 1, 12*time.Second))
+	assert.Len(t, d.latest, 2, "peripherals not seen within the window are forgotten")
+	assert.NotContains(t, d.latest, testAddr1)
+}
+
+func TestDeduplicateReboot(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 5000, 0)))
+	assert.False(t, d.Duplicate(sequenced(testAddr1, 0, time.Second)))
+	assert.True(t, d.Duplicate(sequenced(testAddr1, 0, 2*time.Second)))
+}
+
+func TestDeduplicateContent(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	sd := sensor.Data{
+		Addr:        testAddr1,
+		Temperature: sensor.Float64(21.5),
+		Humidity:    sensor.Float64(40.0),
+		RSSI:        sensor.Int(-70),
+		Timestamp:   dedupTestTime,
+	}
+	assert.False(t, d.Duplicate(sd))
+	sd.RSSI = sensor.Int(-75)
+	sd.Timestamp = dedupTestTime.Add(time.Second)
+	assert.True(t, d.Duplicate(sd), "only measured values are compared")
+	sd.Temperature = sensor.Float64(21.6)
+	assert.False(t, d.Duplicate(sd))
+	sd.Timestamp = dedupTestTime.Add(12 * time.Second)
+	assert.False(t, d.Duplicate(sd), "identical content is accepted again after the window")
+}
+
+type repeatingBLEScanner struct {
+	advertisement ble.Advertisement
+	times         int
+}
+
+func (m repeatingBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	for i := 0; i < m.times; i++ {
+		h(m.advertisement)
+	}
+	return nil
+}
+
+func TestMeasurementsDropDuplicates(t *testing.T) {
+	meas := &Measurements{
+		BLE:         repeatingBLEScanner{advertisement: testAdvertisement, times: 5},
+		Peripherals: peripherals,
+		Dedup:       NewDeduplicator(time.Minute),
+		Logger:      logger,
+	}
+	var received []sensor.Data
+	for sd := range meas.Channel(context.Background()) {
+		received = append(received, sd)
+	}
+	assert.Len(t, received, 1)
+}
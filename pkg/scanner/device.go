@@ -1,8 +1,12 @@
 package scanner
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/go-ble/ble"
-	"github.com/go-ble/ble/examples/lib/dev"
+	"github.com/go-ble/ble/linux"
 )
 
 type DeviceCreator interface {
@@ -13,10 +17,39 @@ type defaultDeviceCreator struct {
 }
 
 func (c defaultDeviceCreator) NewDevice(impl string) (ble.Device, error) {
-	d, err := dev.NewDevice(impl)
+	id, err := deviceID(impl)
+	if err != nil {
+		return nil, err
+	}
+	var opts []ble.Option
+	if id >= 0 {
+		opts = append(opts, ble.OptDeviceID(id))
+	}
+	d, err := linux.NewDevice(opts...)
 	if err != nil {
 		return nil, err
 	}
-	ble.SetDefaultDevice(d)
 	return d, nil
 }
+
+// deviceID returns the HCI device ID of the named device, such as 1 for hci1. The default device has
+// ID -1, which opens the first available device.
+func deviceID(name string) (int, error) {
+	if name == "default" {
+		return -1, nil
+	}
+	n, ok := strings.CutPrefix(name, "hci")
+	id, err := strconv.Atoi(n)
+	if !ok || err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid device name %q, expected default or hciN", name)
+	}
+	return id, nil
+}
+
+// nopDeviceCreator does not open any devices. It is used when scanning without Bluetooth hardware.
+type nopDeviceCreator struct {
+}
+
+func (c nopDeviceCreator) NewDevice(impl string) (ble.Device, error) {
+	return nil, nil
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// Sighting contains what has been seen of a single RuuviTag during discovery
+type Sighting struct {
+	Addr       string
+	DataFormat byte
+	RSSI       int
+	Data       sensor.Data
+	Count      int
+	LastSeen   time.Time
+}
+
+// DiscoveryScanner listens for advertisements of all nearby RuuviTags. It scans with the adapters of the
+// engine, so discovery uses the same devices, recordings and replays as collecting measurements.
+type DiscoveryScanner struct {
+	*Engine
+	mu        sync.Mutex
+	sightings map[string]Sighting
+}
+
+func NewDiscovery(logger *slog.Logger) *DiscoveryScanner {
+	return &DiscoveryScanner{
+		Engine:    NewEngine(logger, nil),
+		sightings: make(map[string]Sighting),
+	}
+}
+
+// Scan listens for RuuviTags with every adapter until the context is done or a replay has finished. If any
+// adapter fails, scanning with the other adapters is stopped.
+func (s *DiscoveryScanner) Scan(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	adapters := s.meas.adapters()
+	errs := make(chan error, len(adapters))
+	for _, adapter := range adapters {
+		go func(adapter Adapter) {
+			err := s.scan(ctx, adapter)
+			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				s.logger.LogAttrs(ctx, slog.LevelError, "Scan failed", slog.String("adapter", adapter.Name), slog.Any("error", err))
+				cancel()
+				errs <- err
+				return
+			}
+			errs <- nil
+		}(adapter)
+	}
+	var scanErr error
+	for range adapters {
+		if err := <-errs; err != nil && scanErr == nil {
+			scanErr = err
+		}
+	}
+	return scanErr
+}
+
+// Sightings returns the RuuviTags seen so far sorted by address
+func (s *DiscoveryScanner) Sightings() []Sighting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sightings := make([]Sighting, 0, len(s.sightings))
+	for _, st := range s.sightings {
+		sightings = append(sightings, st)
+	}
+	sort.Slice(sightings, func(i, j int) bool {
+		return sightings[i].Addr < sightings[j].Addr
+	})
+	return sightings
+}
+
+// scan listens for RuuviTags with the adapter and records the advertisements if a recorder has been set
+func (s *DiscoveryScanner) scan(ctx context.Context, adapter Adapter) error {
+	if r := s.meas.Recorder; r != nil {
+		return adapter.BLE.Scan(ctx, true, func(a ble.Advertisement) {
+			if err := r.Record(adapter.Name, a); err != nil {
+				s.logger.LogAttrs(ctx, slog.LevelError, "Failed to record advertisement", slog.Any("error", err))
+			}
+			if Filter(nil)(a) {
+				s.handle(a)
+			}
+		}, nil)
+	}
+	return adapter.BLE.Scan(ctx, true, s.handle, Filter(nil))
+}
+
+func (s *DiscoveryScanner) handle(a ble.Advertisement) {
+	addr := a.Addr().String()
+	s.meas.mu.RLock()
+	key := s.meas.Keys[addr]
+	s.meas.mu.RUnlock()
+	sd, err := Read(a, key, calibration.Calibration{})
+	if err != nil && !errors.Is(err, sensor.ErrNoKey) {
+		s.logger.LogAttrs(nil, slog.LevelDebug, "Ignoring invalid RuuviTag data", slog.String("addr", addr), slog.Any("error", err))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.sightings[addr]
+	st.Addr = addr
+	st.DataFormat = a.ManufacturerData()[2]
+	st.RSSI = a.RSSI()
+	if err == nil {
+		st.Data = sd
+	}
+	st.Count++
+	st.LastSeen = s.clock.Now()
+	s.sightings[addr] = st
+}
@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscovery(t *testing.T) {
+	scn := NewDiscovery(logger)
+	scn.meas.BLE = repeatingBLEScanner{advertisement: testAdvertisement, times: 3}
+	scn.dev = mockDeviceCreator{device: mockDevice{}}
+	require.NoError(t, scn.Init("default"))
+	defer scn.Close()
+	require.NoError(t, scn.Scan(context.Background()))
+	sightings := scn.Sightings()
+	require.Len(t, sightings, 1)
+	st := sightings[0]
+	assert.Equal(t, testAddr1, st.Addr)
+	assert.Equal(t, byte(3), st.DataFormat)
+	assert.Equal(t, 3, st.Count)
+	assert.Equal(t, 55.0, *st.Data.Temperature)
+}
+
+func TestDiscoveryWithAdapters(t *testing.T) {
+	scn := NewDiscovery(logger)
+	scn.SetAdapters(
+		Adapter{Name: "hci0", BLE: repeatingBLEScanner{advertisement: testAdvertisement, times: 2}},
+		Adapter{Name: "hci1", BLE: repeatingBLEScanner{advertisement: testAdvertisement, times: 3}},
+	)
+	require.NoError(t, scn.Init("hci0", "hci1"))
+	defer scn.Close()
+	require.NoError(t, scn.Scan(context.Background()))
+	sightings := scn.Sightings()
+	require.Len(t, sightings, 1)
+	assert.Equal(t, 5, sightings[0].Count)
+}
+
+func TestDiscoveryFromReplay(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf)
+	require.NoError(t, rec.Record("hci0", testAdvertisement))
+	require.NoError(t, rec.Record("hci0", testAdvertisement))
+	replayer, err := NewReplayer(&buf, 0)
+	require.NoError(t, err)
+	scn := NewDiscovery(logger)
+	scn.SetBLEScanner(replayer)
+	require.NoError(t, scn.Init("default"))
+	defer scn.Close()
+	require.NoError(t, scn.Scan(context.Background()))
+	sightings := scn.Sightings()
+	require.Len(t, sightings, 1)
+	assert.Equal(t, testAddr1, sightings[0].Addr)
+	assert.Equal(t, 2, sightings[0].Count)
+}
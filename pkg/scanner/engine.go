@@ -0,0 +1,753 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/aggregate"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/outlier"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/psychrometrics"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+// ErrClosed is returned when initializing an engine that has been closed
+var ErrClosed = errors.New("scanner is closed")
+
+// Engine scans measurements from peripherals and exports them. A Schedule decides when the engine scans.
+// Scans are cancelled when their context is done or the engine is stopped. Stop, Shutdown and Close are safe
+// to call from any goroutine and more than once.
+type Engine struct {
+	Exporters []exporter.Exporter
+
+	logger      *slog.Logger
+	peripherals map[string]string
+	dev         DeviceCreator
+	meas        *Measurements
+	staleness   *StalenessTracker
+	aggregation []aggregate.Func
+	collectAll  *CollectAll
+	deviceNames []string
+	recovery    RecoveryConfig
+	clock       Clock
+	mu          sync.Mutex
+	// motion and outliers are guarded by mu
+	motion   *MotionDetector
+	outliers *outlier.Filter
+	health   Health
+	alive    time.Time
+	failures int
+	// exportMu guards Exporters while the engine is running
+	exportMu sync.RWMutex
+	// quarantine receives the measurements rejected by the outlier filter
+	quarantine []exporter.Exporter
+	// runs tracks the running scans. New scans are not started once stopped is done.
+	runs sync.WaitGroup
+	// running is the scan started in the background, if any
+	running *Running
+	stopped context.Context
+	stop    context.CancelFunc
+	// drainCtx bounds exporting the measurements received before the engine was shut down
+	drainCtx  context.Context
+	closeOnce sync.Once
+	closeErr  error
+	// devMu guards devices and closed
+	devMu   sync.Mutex
+	devices []ble.Device
+	closed  bool
+}
+
+// Running is a scan running in the background
+type Running struct {
+	done chan struct{}
+	err  error
+}
+
+// Done returns a channel that is closed when the scan has finished
+func (r *Running) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait waits until the scan has finished and returns the error that ended it, if any
+func (r *Running) Wait() error {
+	<-r.done
+	return r.err
+}
+
+func NewEngine(logger *slog.Logger, peripherals map[string]string) *Engine {
+	stopped, stop := context.WithCancel(context.Background())
+	e := &Engine{
+		logger:      logger,
+		peripherals: peripherals,
+		dev:         defaultDeviceCreator{},
+		meas: &Measurements{
+			Peripherals: peripherals,
+			Logger:      logger,
+		},
+		recovery: RecoveryConfig{
+			InitialBackoff: DefaultInitialBackoff,
+			MaxBackoff:     DefaultMaxBackoff,
+		},
+		clock:   realClock{},
+		stopped: stopped,
+		stop:    stop,
+	}
+	e.meas.BLE = defaultBLEScanner{device: e.device}
+	return e
+}
+
+// Run scans according to the schedule until the schedule finishes, the context is done or the engine is
+// stopped. Run returns immediately if the engine has been stopped.
+func (e *Engine) Run(ctx context.Context, schedule Schedule) error {
+	e.mu.Lock()
+	if e.stopped.Err() != nil {
+		e.mu.Unlock()
+		return nil
+	}
+	e.runs.Add(1)
+	e.mu.Unlock()
+	defer e.runs.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(e.stopped, cancel)()
+	return schedule.Run(ctx, e)
+}
+
+// start runs the schedule in the background. If a scan is already running in the background, it is returned
+// instead of starting another one.
+func (e *Engine) start(ctx context.Context, schedule Schedule) *Running {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running != nil {
+		select {
+		case <-e.running.done:
+		default:
+			return e.running
+		}
+	}
+	r := &Running{done: make(chan struct{})}
+	e.running = r
+	go func() {
+		defer close(r.done)
+		if r.err = e.Run(ctx, schedule); r.err != nil {
+			e.logger.LogAttrs(ctx, slog.LevelError, "Scan failed", slog.Any("error", r.err))
+		}
+	}()
+	return r
+}
+
+// Collect scans until a measurement has been received from every peripheral or the context is done.
+// If aggregation is enabled, Collect scans until the context is done and exports aggregated measurements.
+// If the scan fails or the adapter has stalled, the device is recovered before returning.
+func (e *Engine) Collect(ctx context.Context) {
+	meas, errs := e.meas.ChannelWithErrors(ctx)
+	var received bool
+	if len(e.aggregation) > 0 {
+		received = e.doAggregate(ctx, meas)
+	} else {
+		received = e.doExport(ctx, meas)
+	}
+	e.checkStaleness(context.WithoutCancel(ctx))
+	select {
+	case err := <-errs:
+		e.recover(context.WithoutCancel(ctx), err)
+		return
+	default:
+	}
+	if !received && e.stalled() {
+		e.recover(context.WithoutCancel(ctx), ErrStalled)
+	}
+}
+
+// Listen exports measurements immediately as they are received until the context is done.
+// If the scan fails or the adapter stalls, the device is recovered and scanning is resumed.
+func (e *Engine) Listen(ctx context.Context) {
+	for {
+		err := e.listen(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if !e.recover(ctx, err) {
+			return
+		}
+	}
+}
+
+func (e *Engine) listen(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	meas, errs := e.meas.ChannelWithErrors(ctx)
+	var checks, stallChecks <-chan time.Time
+	if e.staleness != nil {
+		ticker := time.NewTicker(e.staleness.threshold / 4)
+		defer ticker.Stop()
+		checks = ticker.C
+	}
+	if e.recovery.StallTimeout > 0 {
+		ticker := time.NewTicker(e.recovery.StallTimeout / 4)
+		defer ticker.Stop()
+		stallChecks = ticker.C
+	}
+	for {
+		select {
+		case <-checks:
+			e.checkStaleness(ctx)
+		case <-stallChecks:
+			if e.stalled() {
+				return ErrStalled
+			}
+		case m, ok := <-meas:
+			if !ok {
+				select {
+				case err := <-errs:
+					return err
+				default:
+					return nil
+				}
+			}
+			if err := e.export(ctx, m); err != nil {
+				e.logger.LogAttrs(ctx, slog.LevelError, "Failed to report measurement", slog.Any("error", err))
+			}
+		case <-ctx.Done():
+			e.drain(ctx, meas)
+			return nil
+		}
+	}
+}
+
+// Stop cancels all running scans and prevents new ones from starting. Stop does not wait for the scans to
+// finish; use Running.Wait or Shutdown for that.
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped.Err() == nil {
+		e.logger.Info("Stopping scanner")
+	}
+	e.stop()
+}
+
+// Shutdown stops scanning and waits until the measurements received so far have been exported, then closes
+// the devices and exporters. If ctx is done before the measurements have been drained, the remaining exports
+// are cancelled and an error is returned.
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.drainCtx = ctx
+	e.mu.Unlock()
+	e.Stop()
+	drained := make(chan struct{})
+	go func() {
+		e.runs.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+		e.logger.Info("Drained measurements")
+	case <-ctx.Done():
+		err = fmt.Errorf("failed to drain measurements: %w", ctx.Err())
+	}
+	return errors.Join(err, e.Close())
+}
+
+// Close stops the scanner and frees allocated resources. Returns the errors from closing the exporters.
+// Close does not wait for exports in progress; use Shutdown to finish them first.
+func (e *Engine) Close() error {
+	e.Stop()
+	e.closeOnce.Do(func() {
+		e.closeErr = e.close()
+	})
+	return e.closeErr
+}
+
+func (e *Engine) close() error {
+	e.devMu.Lock()
+	e.closed = true
+	e.stopDevicesLocked(slog.LevelError)
+	e.devMu.Unlock()
+	e.exportMu.RLock()
+	exporters := append(e.Exporters[:len(e.Exporters):len(e.Exporters)], e.quarantine...)
+	e.exportMu.RUnlock()
+	var errs []error
+	for _, exp := range exporters {
+		if err := exp.Close(); err != nil {
+			e.logger.LogAttrs(nil, slog.LevelError, "Failed to close exporter", slog.String("exporter", exp.Name()), slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("failed to close %s exporter: %w", exp.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// drain exports the measurements remaining in the channel after the scan context is done
+func (e *Engine) drain(ctx context.Context, measurements chan sensor.Data) {
+	drainCtx := e.drainContext(ctx)
+	for m := range measurements {
+		if err := e.export(drainCtx, m); err != nil {
+			e.logger.LogAttrs(ctx, slog.LevelError, "Failed to report measurement", slog.Any("error", err))
+		}
+	}
+}
+
+// drainContext returns the context for exporting measurements after the scan context is done. If the engine
+// is being shut down, draining is bounded by the context given to Shutdown.
+func (e *Engine) drainContext(ctx context.Context) context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.drainCtx != nil {
+		return e.drainCtx
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// SetExporters replaces the exporters while the engine is running. Exports in progress finish before
+// SetExporters returns, so the exporters that are no longer used can be closed afterwards.
+func (e *Engine) SetExporters(exporters []exporter.Exporter) {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+	e.Exporters = exporters
+}
+
+// SetPeripherals atomically replaces the peripherals and their decryption keys and calibrations while
+// the engine is running
+func (e *Engine) SetPeripherals(peripherals map[string]string, keys map[string][]byte, calibrations map[string]calibration.Calibration) {
+	e.mu.Lock()
+	e.peripherals = peripherals
+	e.mu.Unlock()
+	e.meas.SetPeripherals(peripherals, keys, calibrations)
+	if e.staleness != nil {
+		e.staleness.SetPeripherals(peripherals, e.clock.Now())
+	}
+	e.logger.LogAttrs(nil, slog.LevelInfo, "Updated peripherals", slog.Any("peripherals", peripherals))
+}
+
+func (e *Engine) currentPeripherals() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.peripherals
+}
+
+// SetMotionDetector enables exporting measurements immediately and emitting motion events when tags move.
+// Interval schedules keep scanning for motion between collections. A nil detector disables motion
+// detection. SetMotionDetector is safe to call while the engine is running.
+func (e *Engine) SetMotionDetector(d *MotionDetector) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.motion = d
+}
+
+func (e *Engine) motionDetector() *MotionDetector {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.motion
+}
+
+// SetOutlierFilter enables rejecting measurements that contain outlying values before they are exported,
+// aggregated or checked for motion. A nil filter disables filtering. SetOutlierFilter is safe to call while
+// the engine is running.
+func (e *Engine) SetOutlierFilter(f *outlier.Filter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.outliers = f
+}
+
+func (e *Engine) outlierFilter() *outlier.Filter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.outliers
+}
+
+// SetQuarantineExporters sets the exporters that receive the measurements rejected by the outlier filter.
+// The exporters are closed when the engine is closed.
+func (e *Engine) SetQuarantineExporters(exporters []exporter.Exporter) {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+	e.quarantine = exporters
+}
+
+// SetDecryptionKeys sets the AES keys used for decrypting data from peripherals that send encrypted data
+func (e *Engine) SetDecryptionKeys(keys map[string][]byte) {
+	e.meas.Keys = keys
+}
+
+// SetCalibrations sets the calibrations applied to measurements of peripherals
+func (e *Engine) SetCalibrations(calibrations map[string]calibration.Calibration) {
+	e.meas.Calibrations = calibrations
+}
+
+// SetDerivedMetrics sets the psychrometric metrics calculated for each measurement
+func (e *Engine) SetDerivedMetrics(metrics []psychrometrics.Metric) {
+	e.meas.Metrics = metrics
+}
+
+// SetDeduplicator sets the deduplicator used for dropping repeated broadcasts of measurements
+func (e *Engine) SetDeduplicator(dedup *Deduplicator) {
+	e.meas.Dedup = dedup
+}
+
+// SetRecorder sets the recorder that records every received advertisement
+func (e *Engine) SetRecorder(r *Recorder) {
+	e.meas.Recorder = r
+}
+
+// SetBLEScanner makes the engine scan with the given scanner, such as a Replayer, instead of Bluetooth devices.
+// No devices are opened when scanning with a custom scanner.
+func (e *Engine) SetBLEScanner(s BLEScanner) {
+	e.meas.BLE = s
+	e.dev = nopDeviceCreator{}
+}
+
+// SetAdapters makes the engine scan concurrently with the given adapters, such as gateway receivers,
+// instead of Bluetooth devices. No devices are opened when scanning with custom adapters.
+func (e *Engine) SetAdapters(adapters ...Adapter) {
+	e.meas.Adapters = adapters
+	e.dev = nopDeviceCreator{}
+}
+
+// SetCollectAll enables collecting measurements from all RuuviTags in range in addition to the peripherals.
+// Collections finish after no new RuuviTags have been seen within the quiet period instead of after every
+// peripheral has been seen.
+func (e *Engine) SetCollectAll(c *CollectAll) {
+	e.collectAll = c
+	e.meas.All = c
+}
+
+// SetAggregation enables aggregating the measurements of each collection with the given functions.
+// When enabled, Collect listens until its context is done and exports the aggregated measurements
+// instead of exporting the first measurement of each peripheral.
+func (e *Engine) SetAggregation(funcs []aggregate.Func) {
+	e.aggregation = funcs
+}
+
+// SetStalenessThreshold enables emitting events when no measurements have been received from a peripheral
+// within the threshold and when the peripheral recovers. A zero threshold disables the events.
+func (e *Engine) SetStalenessThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		e.staleness = nil
+		return
+	}
+	e.staleness = NewStalenessTracker(e.peripherals, threshold, e.clock.Now())
+}
+
+// Init initializes scanner using the given devices. With multiple devices, each device is scanned
+// concurrently, measurements heard by several devices are deduplicated and each measurement records
+// the device that received it.
+func (e *Engine) Init(devices ...string) error {
+	if len(devices) == 0 {
+		return errors.New("at least one device must be specified")
+	}
+	e.devMu.Lock()
+	if e.closed {
+		e.devMu.Unlock()
+		return ErrClosed
+	}
+	if e.deviceNames != nil {
+		e.devMu.Unlock()
+		return nil
+	}
+	e.deviceNames = devices
+	err := e.openDevicesLocked()
+	e.devMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(devices) > 1 && e.meas.Dedup == nil {
+		e.logger.Info("Enabling deduplication for scanning with multiple devices")
+		e.meas.Dedup = NewDeduplicator(DefaultDedupWindow)
+	}
+	e.mu.Lock()
+	e.alive = e.clock.Now()
+	e.health.Healthy = true
+	e.mu.Unlock()
+	if e.collectAll != nil {
+		e.logger.LogAttrs(nil, slog.LevelInfo, "Reading from all RuuviTags in range", slog.Any("peripherals", e.peripherals))
+	} else if len(e.peripherals) > 0 {
+		e.logger.LogAttrs(nil, slog.LevelInfo, "Reading from peripherals", slog.Any("peripherals", e.peripherals))
+	} else {
+		e.logger.Info("Reading from all nearby BLE peripherals")
+	}
+	return nil
+}
+
+// reopenDevices stops the devices used for scanning and creates them again
+func (e *Engine) reopenDevices() error {
+	e.devMu.Lock()
+	defer e.devMu.Unlock()
+	if e.closed {
+		return ErrClosed
+	}
+	e.stopDevicesLocked(slog.LevelDebug)
+	return e.openDevicesLocked()
+}
+
+// openDevicesLocked creates the devices used for scanning. The caller must hold devMu.
+func (e *Engine) openDevicesLocked() error {
+	devices := make([]ble.Device, 0, len(e.deviceNames))
+	var adapters []Adapter
+	for _, name := range e.deviceNames {
+		d, err := e.dev.NewDevice(name)
+		if err != nil {
+			e.devices = devices
+			e.stopDevicesLocked(slog.LevelDebug)
+			return fmt.Errorf("failed to initialize device %s: %w", name, err)
+		}
+		if d != nil {
+			devices = append(devices, d)
+			adapters = append(adapters, Adapter{Name: name, BLE: deviceScanner{device: d}})
+		}
+	}
+	e.devices = devices
+	if len(adapters) > 1 {
+		e.meas.SetAdapters(adapters)
+	}
+	return nil
+}
+
+// device returns the first device opened for scanning or nil if no device has been opened
+func (e *Engine) device() ble.Device {
+	e.devMu.Lock()
+	defer e.devMu.Unlock()
+	if len(e.devices) == 0 {
+		return nil
+	}
+	return e.devices[0]
+}
+
+// stopDevicesLocked stops the devices used for scanning and logs errors at the given level. The caller must
+// hold devMu.
+func (e *Engine) stopDevicesLocked(level slog.Level) {
+	for _, d := range e.devices {
+		if err := d.Stop(); err != nil {
+			e.logger.LogAttrs(nil, level, "Error while stopping device", slog.Any("error", err))
+		}
+	}
+	e.devices = nil
+}
+
+// doExport exports measurements until one has been received from every peripheral, or when collecting all
+// RuuviTags, until no new RuuviTags have been seen within the quiet period. Reports whether any measurements
+// were received.
+func (e *Engine) doExport(ctx context.Context, measurements chan sensor.Data) bool {
+	peripherals := e.currentPeripherals()
+	seenPeripherals := make(map[string]bool)
+	var quiet <-chan time.Time
+	if e.collectAll != nil && e.collectAll.QuietPeriod > 0 {
+		quiet = e.clock.After(e.collectAll.QuietPeriod)
+	}
+	for {
+		select {
+		case m, ok := <-measurements:
+			if !ok {
+				return len(seenPeripherals) > 0
+			}
+			if !seenPeripherals[m.Addr] && quiet != nil {
+				quiet = e.clock.After(e.collectAll.QuietPeriod)
+			}
+			seenPeripherals[m.Addr] = true
+			if err := e.export(ctx, m); err != nil {
+				e.logger.LogAttrs(ctx, slog.LevelError, "Failed to report measurement", slog.Any("error", err))
+			}
+			if e.collectAll == nil && len(peripherals) > 0 && ContainsKeys(peripherals, seenPeripherals) {
+				return true
+			}
+		case <-quiet:
+			e.logger.LogAttrs(ctx, slog.LevelDebug, "No new RuuviTags seen within quiet period", slog.Int("seen", len(seenPeripherals)))
+			return len(seenPeripherals) > 0
+		case <-ctx.Done():
+			e.drain(ctx, measurements)
+			return len(seenPeripherals) > 0
+		}
+	}
+}
+
+// doAggregate exports the aggregated measurements once the measurements channel is closed and reports
+// whether any measurements were received
+func (e *Engine) doAggregate(ctx context.Context, measurements chan sensor.Data) bool {
+	agg := aggregate.New()
+	received := false
+	for m := range measurements {
+		e.markAlive()
+		received = true
+		e.seen(ctx, m)
+		if !e.accept(ctx, m) {
+			continue
+		}
+		agg.Add(m)
+		// Motion is exported immediately instead of at the end of the window
+		if event, ok := e.observeMotion(m); ok {
+			if err := e.exportWithEvents(ctx, m, event); err != nil {
+				e.logger.LogAttrs(ctx, slog.LevelError, "Failed to report measurement", slog.Any("error", err))
+			}
+		}
+	}
+	// The scan context has expired by the time the window ends
+	ctx = e.drainContext(ctx)
+	for _, m := range agg.Flush(e.aggregation) {
+		if err := e.export(ctx, m); err != nil {
+			e.logger.LogAttrs(ctx, slog.LevelError, "Failed to report measurement", slog.Any("error", err))
+		}
+	}
+	return received
+}
+
+func (e *Engine) export(ctx context.Context, m sensor.Data) error {
+	e.seen(ctx, m)
+	if !e.accept(ctx, m) {
+		return nil
+	}
+	if event, ok := e.observeMotion(m); ok {
+		return e.exportWithEvents(ctx, m, event)
+	}
+	return e.exportWithEvents(ctx, m)
+}
+
+// exportWithEvents exports the events caused by the measurement before the measurement itself
+func (e *Engine) exportWithEvents(ctx context.Context, m sensor.Data, events ...exporter.Event) error {
+	e.markAlive()
+	e.exportMu.RLock()
+	defer e.exportMu.RUnlock()
+	for _, event := range events {
+		exportEvent(ctx, e.logger, e.Exporters, event)
+	}
+	e.logger.LogAttrs(ctx, slog.LevelInfo, "Exporting measurement", slog.Any("measurement", m))
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	for _, exp := range e.Exporters {
+		if err := exp.Export(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seen marks the peripheral of the measurement as seen now and exports a recovery event if the peripheral
+// was missing. The engine clock is used instead of the measurement timestamp, which may come from a gateway
+// or a recording. Measurements rejected by the outlier filter count too, since their tag is faulty rather
+// than missing.
+func (e *Engine) seen(ctx context.Context, m sensor.Data) {
+	if e.staleness == nil {
+		return
+	}
+	event, ok := e.staleness.Seen(m.Addr, e.clock.Now())
+	if !ok {
+		return
+	}
+	e.exportMu.RLock()
+	defer e.exportMu.RUnlock()
+	exportEvent(ctx, e.logger, e.Exporters, event)
+}
+
+// accept passes the measurement to the outlier filter, if any, and reports whether the measurement is accepted.
+// Rejected measurements are counted, logged and exported to the quarantine exporters. Aggregated measurements
+// are not filtered since the raw measurements they are calculated from have already been filtered.
+func (e *Engine) accept(ctx context.Context, m sensor.Data) bool {
+	f := e.outlierFilter()
+	if f == nil || m.Aggregate != "" {
+		return true
+	}
+	r, rejected := f.Check(m)
+	if !rejected {
+		return true
+	}
+	e.mu.Lock()
+	e.health.RejectedMeasurements++
+	e.mu.Unlock()
+	e.logger.LogAttrs(ctx, slog.LevelWarn, "Rejected outlying measurement",
+		slog.String("addr", m.Addr),
+		slog.String("name", m.Name),
+		slog.String("field", r.Field),
+		slog.String("method", string(r.Method)),
+		slog.Float64("value", r.Value),
+		slog.Float64("reference", r.Reference),
+		slog.Float64("limit", r.Limit),
+		slog.Int("rejected", f.Rejected(m.Addr)),
+	)
+	e.exportMu.RLock()
+	defer e.exportMu.RUnlock()
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	for _, exp := range e.quarantine {
+		if err := exp.Export(ctx, m); err != nil {
+			e.logger.LogAttrs(ctx, slog.LevelError, "Failed to quarantine measurement", slog.String("exporter", exp.Name()), slog.Any("error", err))
+		}
+	}
+	return false
+}
+
+// observeMotion passes the measurement to the motion detector, if any. Aggregated measurements are not
+// observed since motion is detected from the raw measurements.
+func (e *Engine) observeMotion(m sensor.Data) (exporter.Event, bool) {
+	d := e.motionDetector()
+	if d == nil || m.Aggregate != "" {
+		return exporter.Event{}, false
+	}
+	event, ok := d.Observe(m)
+	if ok {
+		e.logger.LogAttrs(nil, slog.LevelInfo, "Motion detected", slog.String("addr", m.Addr), slog.String("name", m.Name), slog.Int("movements", event.Movements), slog.Float64("acceleration_change", event.AccelerationChange))
+	}
+	return event, ok
+}
+
+// idle waits until next fires or the context is done and reports whether next fired. With motion
+// detection enabled, the engine keeps scanning while idle and exports the measurements of the tags
+// that move.
+func (e *Engine) idle(ctx context.Context, next <-chan time.Time) bool {
+	if e.motionDetector() == nil {
+		select {
+		case <-next:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		e.watchMotion(watchCtx)
+	}()
+	fired := false
+	select {
+	case <-next:
+		fired = true
+	case <-ctx.Done():
+	}
+	cancel()
+	<-watched
+	return fired
+}
+
+// watchMotion scans until the context is done and exports only the measurements of the tags that move
+func (e *Engine) watchMotion(ctx context.Context) {
+	for m := range e.meas.Channel(ctx) {
+		e.markAlive()
+		e.seen(ctx, m)
+		if !e.accept(ctx, m) {
+			continue
+		}
+		event, ok := e.observeMotion(m)
+		if !ok {
+			continue
+		}
+		if err := e.exportWithEvents(e.drainContext(ctx), m, event); err != nil {
+			e.logger.LogAttrs(ctx, slog.LevelError, "Failed to report measurement", slog.Any("error", err))
+		}
+	}
+}
+
+func (e *Engine) checkStaleness(ctx context.Context) {
+	if e.staleness == nil {
+		return
+	}
+	e.exportMu.RLock()
+	defer e.exportMu.RUnlock()
+	for _, event := range e.staleness.Check(e.clock.Now()) {
+		exportEvent(ctx, e.logger, e.Exporters, event)
+	}
+}
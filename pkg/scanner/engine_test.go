@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/outlier"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+type countingExporter struct {
+	exporter.NoOp
+	closed atomic.Int32
+}
+
+func (e *countingExporter) Close() error {
+	e.closed.Add(1)
+	return nil
+}
+
+func TestStopAndCloseAreIdempotent(t *testing.T) {
+	scn := NewContinuous(logger, peripherals)
+	exp := new(countingExporter)
+	scn.Exporters = []exporter.Exporter{exp}
+	scn.SetBLEScanner(make(feedBLEScanner))
+	require.NoError(t, scn.Init("default"))
+	running := scn.Scan(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			scn.Stop()
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, scn.Close())
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, running.Wait())
+	assert.Equal(t, int32(1), exp.closed.Load(), "exporters are closed once")
+	assert.ErrorIs(t, scn.Init("default"), ErrClosed)
+}
+
+func TestScanReturnsRunningScan(t *testing.T) {
+	scn := NewContinuous(logger, peripherals)
+	exp := chanExporter{ch: make(chan sensor.Data, 1)}
+	scn.Exporters = []exporter.Exporter{exp}
+	feed := make(feedBLEScanner)
+	scn.SetBLEScanner(feed)
+	require.NoError(t, scn.Init("default"))
+	require.NoError(t, scn.Init("default"), "initializing again has no effect")
+	ctx, cancel := context.WithCancel(context.Background())
+	running := scn.Scan(ctx)
+	assert.Same(t, running, scn.Scan(ctx), "a running scan is not started again")
+	feed <- testAdvertisement
+	assert.Equal(t, testAddr1, (<-exp.ch).Addr)
+	select {
+	case <-running.Done():
+		t.Fatal("scan finished before the context was done")
+	default:
+	}
+	cancel()
+	require.NoError(t, running.Wait())
+	next := scn.Scan(context.Background())
+	assert.NotSame(t, running, next, "a new scan is started after the previous one has finished")
+	scn.Stop()
+	require.NoError(t, next.Wait())
+}
+
+func TestScanAfterStop(t *testing.T) {
+	scn := NewContinuous(logger, peripherals)
+	scn.SetBLEScanner(make(feedBLEScanner))
+	require.NoError(t, scn.Init("default"))
+	scn.Stop()
+	require.NoError(t, scn.Scan(context.Background()).Wait(), "scans finish immediately once stopped")
+}
+
+func TestScanWithInvalidInterval(t *testing.T) {
+	scn := NewInterval(logger, peripherals)
+	assert.Error(t, scn.Scan(context.Background(), 0).Wait())
+}
+
+func TestOutlierFilter(t *testing.T) {
+	scn := NewContinuous(logger, peripherals)
+	exp := chanExporter{ch: make(chan sensor.Data)}
+	quarantine := chanExporter{ch: make(chan sensor.Data)}
+	scn.Exporters = []exporter.Exporter{exp}
+	feed := make(feedBLEScanner)
+	scn.SetBLEScanner(feed)
+	f, err := outlier.New([]outlier.Rule{{Field: "temperature", Method: outlier.Rate, Threshold: 6}}, nil)
+	require.NoError(t, err)
+	scn.SetOutlierFilter(f)
+	scn.SetQuarantineExporters([]exporter.Exporter{quarantine})
+	require.NoError(t, scn.Init("default"))
+	running := scn.Scan(context.Background())
+	advertise := func(temperature float64) {
+		feed <- mockAdvertisement{addr: testAddr1, manufacturerData: sensor.EncodeSensorFormat5(sensor.Data{Temperature: sensor.Float64(temperature)})}
+	}
+	advertise(20)
+	assert.Equal(t, 20.0, *(<-exp.ch).Temperature)
+	advertise(84)
+	assert.Equal(t, 84.0, *(<-quarantine.ch).Temperature)
+	advertise(20.05)
+	assert.Equal(t, 20.05, *(<-exp.ch).Temperature)
+	assert.Equal(t, 1, scn.Health().RejectedMeasurements)
+	scn.Stop()
+	require.NoError(t, running.Wait())
+}
+
+func TestMissingDecryptionKeyIsWarnedOnce(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEngine(slog.New(slog.NewTextHandler(buf, nil)), peripherals)
+	encrypted := mockAdvertisement{addr: testAddr1, manufacturerData: append([]byte{0x99, 0x04, 0x08}, make([]byte, 20)...)}
+	e.SetBLEScanner(repeatingBLEScanner{advertisement: encrypted, times: 5})
+	require.NoError(t, e.Init("default"))
+	e.Collect(context.Background())
+	assert.Equal(t, 1, strings.Count(buf.String(), "no decryption key is configured"))
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Equal(t, 5, e.Health().UndecryptableMeasurements)
+}
@@ -2,49 +2,205 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"sync"
 
 	"github.com/go-ble/ble"
 
+	"github.com/niktheblak/ruuvitag-gollector/pkg/calibration"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/psychrometrics"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
 )
 
 const BufferSize = 128
 
 type Measurements struct {
-	BLE         BLEScanner
-	Peripherals map[string]string
-	Logger      *slog.Logger
+	BLE BLEScanner
+	// Adapters are scanned concurrently when there are several of them. If empty, BLE is used.
+	Adapters     []Adapter
+	Peripherals  map[string]string
+	Keys         map[string][]byte
+	Calibrations map[string]calibration.Calibration
+	Metrics      []psychrometrics.Metric
+	Dedup        *Deduplicator
+	// Recorder records every received advertisement before filtering, if set
+	Recorder *Recorder
+	// All enables collecting all RuuviTags in range in addition to the peripherals, if set
+	All    *CollectAll
+	Logger *slog.Logger
+
+	// mu guards Adapters, Peripherals, Keys, Calibrations, warnedNoKey and undecryptable while scanning
+	mu sync.RWMutex
+	// warnedNoKey contains the peripherals that have been warned about missing a decryption key
+	warnedNoKey map[string]bool
+	// undecryptable is the number of encrypted advertisements dropped because no decryption key is configured
+	undecryptable int
+}
+
+// SetAdapters replaces the adapters used by the scans started after the call
+func (s *Measurements) SetAdapters(adapters []Adapter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Adapters = adapters
+}
+
+// SetPeripherals atomically replaces the peripherals and their decryption keys and calibrations.
+// Scans in progress use the new configuration for the advertisements they receive after the call.
+func (s *Measurements) SetPeripherals(peripherals map[string]string, keys map[string][]byte, calibrations map[string]calibration.Calibration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Peripherals = peripherals
+	s.Keys = keys
+	s.Calibrations = calibrations
+	// Peripherals that are still missing a key are warned about again after a configuration change
+	s.warnedNoKey = nil
+}
+
+// noKey counts an encrypted advertisement of the peripheral that could not be decrypted and reports whether
+// it is the first one since the configuration was set
+func (s *Measurements) noKey(addr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.undecryptable++
+	if s.warnedNoKey[addr] {
+		return false
+	}
+	if s.warnedNoKey == nil {
+		s.warnedNoKey = make(map[string]bool)
+	}
+	s.warnedNoKey[addr] = true
+	return true
+}
+
+// Undecryptable returns the number of encrypted advertisements dropped because no decryption key is configured
+func (s *Measurements) Undecryptable() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.undecryptable
+}
+
+func (s *Measurements) peripherals() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Peripherals
 }
 
 // Channel creates a channel that will receive measurements read from all registered peripherals.
 // The cancel function should be called after the client is done with receiving measurements or wishes
 // to abort the scan.
 func (s *Measurements) Channel(ctx context.Context) chan sensor.Data {
+	ch, _ := s.ChannelWithErrors(ctx)
+	return ch
+}
+
+// adapters returns the adapters to scan with. Without adapters, the scan uses BLE.
+func (s *Measurements) adapters() []Adapter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.Adapters) == 0 {
+		return []Adapter{{BLE: s.BLE}}
+	}
+	return s.Adapters
+}
+
+// ChannelWithErrors works like Channel but also returns a channel that receives the error if the scan fails.
+// The error is sent before the measurements channel is closed. If any adapter fails, scanning with the other
+// adapters is stopped.
+func (s *Measurements) ChannelWithErrors(ctx context.Context) (chan sensor.Data, chan error) {
 	if s.Logger == nil {
 		s.Logger = slog.Default()
 	}
+	adapters := s.adapters()
 	ch := make(chan sensor.Data, BufferSize)
-	go func() {
-		err := s.BLE.Scan(ctx, true, func(a ble.Advertisement) {
-			addr := a.Addr().String()
-			s.Logger.LogAttrs(ctx, slog.LevelDebug, "Read sensor data from device", slog.String("addr", addr))
-			sensorData, err := Read(a)
-			if err != nil {
-				LogInvalidData(ctx, s.Logger, a.ManufacturerData(), err)
-				return
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, adapter := range adapters {
+		wg.Add(1)
+		go func(adapter Adapter) {
+			defer wg.Done()
+			err := s.scan(ctx, adapter, ch)
+			switch {
+			case errors.Is(err, context.Canceled):
+			case errors.Is(err, context.DeadlineExceeded):
+			case err == nil:
+			default:
+				s.Logger.LogAttrs(ctx, slog.LevelError, "Scan failed", slog.String("adapter", adapter.Name), slog.Any("error", err))
+				select {
+				case errs <- err:
+					cancel()
+				default:
+				}
 			}
-			sensorData.Name = s.Peripherals[addr]
-			ch <- sensorData
-		}, Filter(s.Peripherals))
-		switch err {
-		case context.Canceled:
-		case context.DeadlineExceeded:
-		case nil:
-		default:
-			s.Logger.LogAttrs(ctx, slog.LevelError, "Scan failed", slog.Any("error", err))
-		}
+		}(adapter)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
 		close(ch)
 	}()
-	return ch
+	return ch, errs
+}
+
+func (s *Measurements) scan(ctx context.Context, adapter Adapter, ch chan sensor.Data) error {
+	filter := func(a ble.Advertisement) bool {
+		if s.All != nil {
+			return s.All.Filter(s.peripherals())(a)
+		}
+		return Filter(s.peripherals())(a)
+	}
+	if s.Recorder != nil {
+		handler := s.handler(ctx, adapter, ch)
+		return adapter.BLE.Scan(ctx, true, func(a ble.Advertisement) {
+			if err := s.Recorder.Record(adapter.Name, a); err != nil {
+				s.Logger.LogAttrs(ctx, slog.LevelError, "Failed to record advertisement", slog.Any("error", err))
+			}
+			if filter(a) {
+				handler(a)
+			}
+		}, nil)
+	}
+	return adapter.BLE.Scan(ctx, true, s.handler(ctx, adapter, ch), filter)
+}
+
+func (s *Measurements) handler(ctx context.Context, adapter Adapter, ch chan sensor.Data) ble.AdvHandler {
+	return func(a ble.Advertisement) {
+		addr := a.Addr().String()
+		s.Logger.LogAttrs(ctx, slog.LevelDebug, "Read sensor data from device", slog.String("addr", addr), slog.String("adapter", adapter.Name))
+		s.mu.RLock()
+		peripherals, key, cal := s.Peripherals, s.Keys[addr], s.Calibrations[addr]
+		s.mu.RUnlock()
+		sensorData, err := Read(a, key, cal)
+		if errors.Is(err, sensor.ErrNoKey) {
+			// Tags broadcast several times a second, so only the first advertisement of each tag is warned about
+			level := slog.LevelDebug
+			if s.noKey(addr) {
+				level = slog.LevelWarn
+			}
+			s.Logger.LogAttrs(ctx, level, "Received encrypted data but no decryption key is configured for device", slog.String("addr", addr))
+			return
+		}
+		if err != nil {
+			LogInvalidData(ctx, s.Logger, a.ManufacturerData(), err)
+			return
+		}
+		if s.Dedup != nil && s.Dedup.Duplicate(sensorData) {
+			s.Logger.LogAttrs(ctx, slog.LevelDebug, "Dropping duplicate measurement", slog.String("addr", addr), slog.String("adapter", adapter.Name))
+			return
+		}
+		psychrometrics.Calculate(&sensorData, s.Metrics)
+		name, ok := peripherals[addr]
+		if !ok && s.All != nil {
+			name = s.All.Name(addr)
+		}
+		sensorData.Name = name
+		if sensorData.Adapter == "" {
+			sensorData.Adapter = adapter.Name
+		}
+		select {
+		case ch <- sensorData:
+		case <-ctx.Done():
+		}
+	}
 }
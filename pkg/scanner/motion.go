@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+const (
+	DefaultMovementThreshold = 1
+	DefaultMotionDebounce    = 30 * time.Second
+)
+
+// movementCounterRange is the number of values of the movement counter before it wraps around
+const movementCounterRange = 255
+
+// MotionConfig configures when a tag is considered to have moved
+type MotionConfig struct {
+	// MovementThreshold is how many times the movement counter must have increased since the previous
+	// measurement. Zero disables the movement counter.
+	MovementThreshold int
+	// AccelerationThreshold is how much the acceleration must have changed since the previous measurement
+	// in mG. Zero disables acceleration changes.
+	AccelerationThreshold float64
+	// Debounce is the minimum time between motion events of a tag
+	Debounce time.Duration
+}
+
+// Enabled reports whether the configuration detects motion at all
+func (c MotionConfig) Enabled() bool {
+	return c.MovementThreshold > 0 || c.AccelerationThreshold > 0
+}
+
+// MotionDetector detects tags that have moved by comparing consecutive measurements of each tag.
+// MotionDetector is safe for concurrent use.
+type MotionDetector struct {
+	defaults *MotionConfig
+	tags     map[string]MotionConfig
+	mu       sync.Mutex
+	previous map[string]motionState
+}
+
+type motionState struct {
+	data      sensor.Data
+	triggered time.Time
+}
+
+// NewMotionDetector creates a detector that uses the configuration in tags for the tags in it and defaults
+// for the other tags. Other tags are not watched if defaults is nil.
+func NewMotionDetector(defaults *MotionConfig, tags map[string]MotionConfig) *MotionDetector {
+	return &MotionDetector{
+		defaults: defaults,
+		tags:     tags,
+		previous: make(map[string]motionState),
+	}
+}
+
+func (d *MotionDetector) config(addr string) (MotionConfig, bool) {
+	if cfg, ok := d.tags[addr]; ok {
+		return cfg, cfg.Enabled()
+	}
+	if d.defaults != nil {
+		return *d.defaults, d.defaults.Enabled()
+	}
+	return MotionConfig{}, false
+}
+
+// Observe records the measurement and returns a motion event if the tag has moved since its previous
+// measurement. Motion is not reported again for a tag until the debounce time has passed.
+func (d *MotionDetector) Observe(m sensor.Data) (exporter.Event, bool) {
+	cfg, ok := d.config(m.Addr)
+	if !ok {
+		return exporter.Event{}, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	prev, seen := d.previous[m.Addr]
+	state := motionState{data: m, triggered: prev.triggered}
+	defer func() {
+		d.previous[m.Addr] = state
+	}()
+	if !seen || m.Timestamp.Before(prev.data.Timestamp) {
+		return exporter.Event{}, false
+	}
+	movements := movementsBetween(prev.data, m)
+	change := accelerationChange(prev.data, m)
+	moved := (cfg.MovementThreshold > 0 && movements >= cfg.MovementThreshold) ||
+		(cfg.AccelerationThreshold > 0 && change >= cfg.AccelerationThreshold)
+	if !moved {
+		return exporter.Event{}, false
+	}
+	if !prev.triggered.IsZero() && m.Timestamp.Sub(prev.triggered) < cfg.Debounce {
+		return exporter.Event{}, false
+	}
+	state.triggered = m.Timestamp
+	return exporter.Event{
+		Type:               exporter.Motion,
+		Addr:               m.Addr,
+		Name:               m.Name,
+		LastSeen:           prev.data.Timestamp,
+		Timestamp:          m.Timestamp,
+		Movements:          movements,
+		AccelerationChange: change,
+	}, true
+}
+
+// movementsBetween returns how many times the movement counter has increased between the measurements
+func movementsBetween(prev, cur sensor.Data) int {
+	if prev.MovementCounter == nil || cur.MovementCounter == nil {
+		return 0
+	}
+	n := *cur.MovementCounter - *prev.MovementCounter
+	if n < 0 {
+		n += movementCounterRange
+	}
+	return n
+}
+
+// accelerationChange returns the magnitude of the change in acceleration between the measurements in mG
+func accelerationChange(prev, cur sensor.Data) float64 {
+	if prev.AccelerationX == nil || prev.AccelerationY == nil || prev.AccelerationZ == nil ||
+		cur.AccelerationX == nil || cur.AccelerationY == nil || cur.AccelerationZ == nil {
+		return 0
+	}
+	dx := float64(*cur.AccelerationX - *prev.AccelerationX)
+	dy := float64(*cur.AccelerationY - *prev.AccelerationY)
+	dz := float64(*cur.AccelerationZ - *prev.AccelerationZ)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func movement(addr string, counter int, ts time.Time) sensor.Data {
+	return sensor.Data{Addr: addr, Name: "Test", MovementCounter: sensor.Int(counter), Timestamp: ts}
+}
+
+func acceleration(addr string, x, y, z int, ts time.Time) sensor.Data {
+	return sensor.Data{Addr: addr, AccelerationX: sensor.Int(x), AccelerationY: sensor.Int(y), AccelerationZ: sensor.Int(z), Timestamp: ts}
+}
+
+func TestMotionDetectorMovementCounter(t *testing.T) {
+	d := NewMotionDetector(&MotionConfig{MovementThreshold: 2}, nil)
+	_, ok := d.Observe(movement(testAddr1, 10, cronTestStart))
+	assert.False(t, ok, "the first measurement is the baseline")
+	_, ok = d.Observe(movement(testAddr1, 11, cronTestStart.Add(time.Second)))
+	assert.False(t, ok, "below the threshold")
+	event, ok := d.Observe(movement(testAddr1, 13, cronTestStart.Add(2*time.Second)))
+	require.True(t, ok)
+	assert.Equal(t, exporter.Motion, event.Type)
+	assert.Equal(t, testAddr1, event.Addr)
+	assert.Equal(t, "Test", event.Name)
+	assert.Equal(t, 2, event.Movements)
+	assert.Equal(t, cronTestStart.Add(time.Second), event.LastSeen)
+	assert.Equal(t, cronTestStart.Add(2*time.Second), event.Timestamp)
+}
+
+func TestMotionDetectorMovementCounterWraps(t *testing.T) {
+	d := NewMotionDetector(&MotionConfig{MovementThreshold: 1}, nil)
+	d.Observe(movement(testAddr1, 253, cronTestStart))
+	event, ok := d.Observe(movement(testAddr1, 1, cronTestStart.Add(time.Second)))
+	require.True(t, ok)
+	assert.Equal(t, 3, event.Movements)
+}
+
+func TestMotionDetectorAcceleration(t *testing.T) {
+	d := NewMotionDetector(&MotionConfig{AccelerationThreshold: 350}, nil)
+	d.Observe(acceleration(testAddr1, 0, 0, 1000, cronTestStart))
+	_, ok := d.Observe(acceleration(testAddr1, 300, 0, 1000, cronTestStart.Add(time.Second)))
+	assert.False(t, ok, "below the threshold")
+	event, ok := d.Observe(acceleration(testAddr1, 300, 400, 1000, cronTestStart.Add(2*time.Second)))
+	require.True(t, ok)
+	assert.InDelta(t, 400, event.AccelerationChange, 0.001)
+	_, ok = d.Observe(acceleration(testAddr1, 0, 0, 1000, cronTestStart.Add(3*time.Second)))
+	assert.True(t, ok, "changes are measured from the previous measurement")
+}
+
+func TestMotionDetectorDebounce(t *testing.T) {
+	d := NewMotionDetector(&MotionConfig{MovementThreshold: 1, Debounce: time.Minute}, nil)
+	d.Observe(movement(testAddr1, 1, cronTestStart))
+	_, ok := d.Observe(movement(testAddr1, 2, cronTestStart.Add(time.Second)))
+	assert.True(t, ok)
+	_, ok = d.Observe(movement(testAddr1, 3, cronTestStart.Add(30*time.Second)))
+	assert.False(t, ok, "motion is debounced")
+	event, ok := d.Observe(movement(testAddr1, 4, cronTestStart.Add(61*time.Second)))
+	assert.True(t, ok)
+	assert.Equal(t, 1, event.Movements)
+	_, ok = d.Observe(movement(testAddr1, 5, cronTestStart.Add(time.Second)))
+	assert.False(t, ok, "out of order measurements are ignored")
+}
+
+func TestMotionDetectorPerTag(t *testing.T) {
+	d := NewMotionDetector(nil, map[string]MotionConfig{
+		testAddr1: {MovementThreshold: 1},
+		testAddr2: {},
+	})
+	for _, addr := range []string{testAddr1, testAddr2, testAddr3} {
+		d.Observe(movement(addr, 1, cronTestStart))
+	}
+	_, ok := d.Observe(movement(testAddr1, 2, cronTestStart.Add(time.Second)))
+	assert.True(t, ok)
+	_, ok = d.Observe(movement(testAddr2, 2, cronTestStart.Add(time.Second)))
+	assert.False(t, ok, "motion detection is disabled for the tag")
+	_, ok = d.Observe(movement(testAddr3, 2, cronTestStart.Add(time.Second)))
+	assert.False(t, ok, "tags without settings are not watched without defaults")
+
+	d = NewMotionDetector(&MotionConfig{MovementThreshold: 5}, map[string]MotionConfig{testAddr1: {MovementThreshold: 1}})
+	d.Observe(movement(testAddr1, 1, cronTestStart))
+	d.Observe(movement(testAddr3, 1, cronTestStart))
+	_, ok = d.Observe(movement(testAddr1, 2, cronTestStart.Add(time.Second)))
+	assert.True(t, ok, "tag settings override the defaults")
+	_, ok = d.Observe(movement(testAddr3, 2, cronTestStart.Add(time.Second)))
+	assert.False(t, ok)
+}
+
+type chanEventExporter struct {
+	chanExporter
+	events chan exporter.Event
+}
+
+func (e chanEventExporter) ExportEvent(ctx context.Context, event exporter.Event) error {
+	e.events <- event
+	return nil
+}
+
+func TestIdleExportsMotion(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	exp := chanEventExporter{chanExporter: chanExporter{ch: make(chan sensor.Data)}, events: make(chan exporter.Event)}
+	e.Exporters = []exporter.Exporter{exp}
+	feed := make(feedBLEScanner)
+	e.SetBLEScanner(feed)
+	e.SetMotionDetector(NewMotionDetector(&MotionConfig{MovementThreshold: 1}, nil))
+	require.NoError(t, e.Init("default"))
+	advertise := func(counter int) {
+		feed <- mockAdvertisement{addr: testAddr1, manufacturerData: sensor.EncodeSensorFormat5(sensor.Data{MovementCounter: sensor.Int(counter)})}
+	}
+	next := make(chan time.Time)
+	fired := make(chan bool)
+	go func() {
+		fired <- e.idle(context.Background(), next)
+	}()
+	advertise(1)
+	advertise(1)
+	advertise(2)
+	event := <-exp.events
+	assert.Equal(t, exporter.Motion, event.Type)
+	assert.Equal(t, 1, event.Movements)
+	m := <-exp.ch
+	assert.Equal(t, testAddr1, m.Addr)
+	assert.Equal(t, 2, *m.MovementCounter)
+	next <- time.Now()
+	assert.True(t, <-fired)
+}
+
+func TestIdleWithoutMotionDetection(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	e.SetBLEScanner(blockingBLEScanner{started: make(chan struct{})})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, e.idle(ctx, nil))
+}
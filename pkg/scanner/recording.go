@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+// Record is an advertisement stored in a recording. Recordings are JSON Lines files with one record per line.
+type Record struct {
+	Timestamp        time.Time `json:"ts"`
+	Addr             string    `json:"addr"`
+	ManufacturerData string    `json:"manufacturer_data"`
+	RSSI             int       `json:"rssi"`
+	Adapter          string    `json:"adapter,omitempty"`
+}
+
+// Recorder writes received advertisements to a recording. Recorder is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	clock Clock
+}
+
+// NewRecorder creates a recorder that writes records to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		enc:   json.NewEncoder(w),
+		clock: realClock{},
+	}
+}
+
+// Record writes the advertisement received by the given adapter to the recording
+func (r *Recorder) Record(adapter string, a ble.Advertisement) error {
+	rec := Record{
+		Timestamp:        r.clock.Now(),
+		Addr:             a.Addr().String(),
+		ManufacturerData: hex.EncodeToString(a.ManufacturerData()),
+		RSSI:             a.RSSI(),
+		Adapter:          adapter,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(rec)
+}
+
+// Replayer is a BLEScanner that replays the advertisements of a recording. Each scan resumes where the
+// previous one ended and the recorded time between advertisements is reproduced, divided by the replay speed.
+// Time in the recording does not advance between scans. Scanning ends when the recording has been replayed.
+type Replayer struct {
+	// Speed is the replay speed relative to the original speed. Zero replays as fast as possible.
+	Speed float64
+
+	mu      sync.Mutex
+	records []Record
+	next    int
+	clock   Clock
+}
+
+// NewReplayer creates a replayer from the records read from r
+func NewReplayer(r io.Reader, speed float64) (*Replayer, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		if _, err := hex.DecodeString(rec.ManufacturerData); err != nil {
+			return nil, fmt.Errorf("invalid manufacturer data on line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewReplayerFromRecords(records, speed), nil
+}
+
+// NewReplayerFromRecords creates a replayer that replays the given records
+func NewReplayerFromRecords(records []Record, speed float64) *Replayer {
+	return &Replayer{
+		Speed:   speed,
+		records: records,
+		clock:   realClock{},
+	}
+}
+
+// OpenReplay creates a replayer from the recording file at path
+func OpenReplay(path string, speed float64) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayer(f, speed)
+}
+
+// Remaining returns the number of records that have not been replayed yet
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records) - r.next
+}
+
+func (r *Replayer) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	var start time.Time
+	began := r.clock.Now()
+	for {
+		// The lock is not held while waiting or calling the handler so that the handler can call back into
+		// the replayer and Remaining does not block for the whole replay
+		r.mu.Lock()
+		i := r.next
+		if i >= len(r.records) {
+			r.mu.Unlock()
+			return nil
+		}
+		rec := r.records[i]
+		r.mu.Unlock()
+		if start.IsZero() {
+			start = rec.Timestamp
+		}
+		if r.Speed > 0 {
+			due := time.Duration(float64(rec.Timestamp.Sub(start)) / r.Speed)
+			if wait := due - r.clock.Now().Sub(began); wait > 0 {
+				select {
+				case <-r.clock.After(wait):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		// Another scan may have replayed the record in the meantime
+		claimed := r.next == i
+		if claimed {
+			r.next++
+		}
+		r.mu.Unlock()
+		if !claimed {
+			continue
+		}
+		a := replayedAdvertisement{rec}
+		if f == nil || f(a) {
+			h(a)
+		}
+	}
+}
+
+type replayedAdvertisement struct {
+	rec Record
+}
+
+func (a replayedAdvertisement) LocalName() string {
+	return ""
+}
+
+func (a replayedAdvertisement) ManufacturerData() []byte {
+	data, _ := hex.DecodeString(a.rec.ManufacturerData)
+	return data
+}
+
+func (a replayedAdvertisement) ServiceData() []ble.ServiceData {
+	return nil
+}
+
+func (a replayedAdvertisement) Services() []ble.UUID {
+	return nil
+}
+
+func (a replayedAdvertisement) OverflowService() []ble.UUID {
+	return nil
+}
+
+func (a replayedAdvertisement) TxPowerLevel() int {
+	return 0
+}
+
+func (a replayedAdvertisement) Connectable() bool {
+	return false
+}
+
+func (a replayedAdvertisement) SolicitedService() []ble.UUID {
+	return nil
+}
+
+func (a replayedAdvertisement) RSSI() int {
+	return a.rec.RSSI
+}
+
+func (a replayedAdvertisement) Addr() ble.Addr {
+	return ble.NewAddr(a.rec.Addr)
+}
+
+func (a replayedAdvertisement) Timestamp() time.Time {
+	return a.rec.Timestamp
+}
+
+func (a replayedAdvertisement) Adapter() string {
+	return a.rec.Adapter
+}
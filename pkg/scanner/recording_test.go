@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	other := mockAdvertisement{addr: testAddr2, manufacturerData: []byte{0x4c, 0x00, 0x02, 0x15}, rssi: -80}
+	ruuvi := mockAdvertisement{addr: testAddr1, manufacturerData: testData, rssi: -60}
+	buf := new(bytes.Buffer)
+	recorder := NewRecorder(buf)
+	recorder.clock = newFakeClock(replayStart)
+	source := replayOf(other, ruuvi)
+	source.Speed = 0
+	meas := &Measurements{
+		Adapters:    []Adapter{{Name: "hci0", BLE: source}},
+		Peripherals: peripherals,
+		Recorder:    recorder,
+		Logger:      logger,
+	}
+	var received []sensor.Data
+	for sd := range meas.Channel(context.Background()) {
+		received = append(received, sd)
+	}
+	require.Len(t, received, 1)
+	recorded := received[0]
+	assert.Equal(t, testAddr1, recorded.Addr)
+	assert.Equal(t, "hci0", recorded.Adapter)
+
+	replayer, err := NewReplayer(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, replayer.Remaining(), "advertisements are recorded before filtering")
+	assert.Equal(t, Record{
+		Timestamp:        replayStart,
+		Addr:             testAddr2,
+		ManufacturerData: "4c000215",
+		RSSI:             -80,
+		Adapter:          "hci0",
+	}, replayer.records[0])
+	meas = &Measurements{
+		BLE:         replayer,
+		Peripherals: peripherals,
+		Logger:      logger,
+	}
+	var replayed []sensor.Data
+	for sd := range meas.Channel(context.Background()) {
+		replayed = append(replayed, sd)
+	}
+	require.Len(t, replayed, 1)
+	assert.Equal(t, recorded.Temperature, replayed[0].Temperature)
+	assert.Equal(t, recorded.RawData, replayed[0].RawData)
+	assert.Equal(t, -60, *replayed[0].RSSI)
+	assert.True(t, replayStart.Equal(replayed[0].Timestamp), "replayed measurements keep the recorded timestamp")
+	assert.Equal(t, "hci0", replayed[0].Adapter, "replayed measurements keep the recorded adapter")
+	assert.Equal(t, 0, replayer.Remaining())
+}
+
+func TestReplayerSpeed(t *testing.T) {
+	records := []Record{
+		{Timestamp: replayStart, Addr: testAddr1},
+		{Timestamp: replayStart.Add(10 * time.Second), Addr: testAddr2},
+		{Timestamp: replayStart.Add(30 * time.Second), Addr: testAddr3},
+	}
+	replayer := NewReplayerFromRecords(records, 2)
+	clock := newFakeClock(cronTestStart)
+	replayer.clock = clock
+	received := make(chan string, len(records))
+	done := make(chan error)
+	go func() {
+		done <- replayer.Scan(context.Background(), true, func(a ble.Advertisement) {
+			received <- a.Addr().String()
+		}, nil)
+	}()
+	assert.Equal(t, testAddr1, <-received)
+	assert.Equal(t, cronTestStart.Add(5*time.Second), <-clock.waiting)
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, testAddr2, <-received)
+	assert.Equal(t, cronTestStart.Add(15*time.Second), <-clock.waiting)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, testAddr3, <-received)
+	require.NoError(t, <-done)
+}
+
+func TestReplayerResumesAfterCancel(t *testing.T) {
+	replayer := replayOf(testAdvertisement, testAdvertisement)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := replayer.Scan(ctx, true, func(a ble.Advertisement) {
+		cancel()
+	}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, replayer.Remaining())
+}
+
+func TestReplayerHandlerCallsBack(t *testing.T) {
+	replayer := replayOf(testAdvertisement, testAdvertisement, testAdvertisement)
+	replayer.Speed = 0
+	var remaining []int
+	err := replayer.Scan(context.Background(), true, func(a ble.Advertisement) {
+		remaining = append(remaining, replayer.Remaining())
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 0}, remaining)
+}
+
+func TestReplayerInvalidRecord(t *testing.T) {
+	input := `{"ts":"2024-01-01T00:00:00Z","addr":"cc:ca:7e:52:cc:34","manufacturer_data":"99040300","rssi":-60}
+{"ts":"2024-01-01T00:00:01Z","addr":"cc:ca:7e:52:cc:34","manufacturer_data":"xyz","rssi":-60}
+`
+	_, err := NewReplayer(strings.NewReader(input), 1)
+	assert.ErrorContains(t, err, "line 2")
+	_, err = NewReplayer(strings.NewReader("not json\n"), 1)
+	assert.ErrorContains(t, err, "line 1")
+}
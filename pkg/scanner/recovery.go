@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+)
+
+// ErrStalled is the cause of a recovery when no measurements have been received within the stall timeout
+var ErrStalled = errors.New("no measurements received within stall timeout")
+
+// RecoveryConfig configures how the Bluetooth adapter is recovered after scan failures
+type RecoveryConfig struct {
+	// StallTimeout is how long scanning may go on without receiving any measurements before the adapter
+	// is considered stalled. Zero disables stall detection.
+	StallTimeout time.Duration
+	// InitialBackoff is the delay before the second recovery attempt. The delay doubles after each
+	// consecutive failed attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between recovery attempts
+	MaxBackoff time.Duration
+}
+
+// Health is the health status of the scanner
+type Health struct {
+	Healthy          bool      `json:"healthy"`
+	LastMeasurement  time.Time `json:"last_measurement"`
+	RecoveryAttempts int       `json:"recovery_attempts"`
+	LastRecovery     time.Time `json:"last_recovery"`
+	LastError        string    `json:"last_error,omitempty"`
+	// RejectedMeasurements is the number of measurements rejected by the outlier filter
+	RejectedMeasurements int `json:"rejected_measurements"`
+	// UndecryptableMeasurements is the number of encrypted advertisements dropped because no decryption key
+	// is configured for the tag
+	UndecryptableMeasurements int `json:"undecryptable_measurements"`
+}
+
+// SetRecovery sets how the Bluetooth adapter is recovered after scan failures
+func (e *Engine) SetRecovery(cfg RecoveryConfig) {
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	e.recovery = cfg
+}
+
+// Health returns the current health status of the scanner
+func (e *Engine) Health() Health {
+	e.mu.Lock()
+	health := e.health
+	e.mu.Unlock()
+	health.UndecryptableMeasurements = e.meas.Undecryptable()
+	return health
+}
+
+// recover stops the devices and creates them again until it succeeds or the context is done. Consecutive
+// attempts without receiving measurements in between are delayed with exponential backoff.
+func (e *Engine) recover(ctx context.Context, cause error) bool {
+	for {
+		e.mu.Lock()
+		failures := e.failures
+		e.failures++
+		e.health.Healthy = false
+		e.health.RecoveryAttempts++
+		e.health.LastRecovery = e.clock.Now()
+		e.health.LastError = cause.Error()
+		attempts := e.health.RecoveryAttempts
+		e.mu.Unlock()
+		if backoff := e.backoff(failures); backoff > 0 {
+			e.logger.LogAttrs(ctx, slog.LevelInfo, "Waiting before recovering Bluetooth adapter", slog.Duration("backoff", backoff))
+			select {
+			case <-e.clock.After(backoff):
+			case <-ctx.Done():
+				return false
+			case <-e.stopped.Done():
+				return false
+			}
+		}
+		e.logger.LogAttrs(ctx, slog.LevelWarn, "Recovering Bluetooth adapter", slog.Any("devices", e.deviceNames), slog.Int("attempt", attempts), slog.Any("cause", cause))
+		err := e.reopenDevices()
+		if errors.Is(err, ErrClosed) {
+			return false
+		}
+		if err == nil {
+			e.mu.Lock()
+			e.alive = e.clock.Now()
+			e.mu.Unlock()
+			e.logger.LogAttrs(ctx, slog.LevelInfo, "Bluetooth adapter recreated", slog.Any("devices", e.deviceNames), slog.Int("attempt", attempts))
+			return true
+		}
+		e.logger.LogAttrs(ctx, slog.LevelError, "Failed to recreate Bluetooth adapter", slog.Any("devices", e.deviceNames), slog.Int("attempt", attempts), slog.Any("error", err))
+		cause = err
+	}
+}
+
+func (e *Engine) backoff(failures int) time.Duration {
+	if failures == 0 {
+		return 0
+	}
+	backoff := e.recovery.InitialBackoff
+	for i := 1; i < failures && backoff < e.recovery.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, e.recovery.MaxBackoff)
+}
+
+// markAlive records that a measurement was received
+func (e *Engine) markAlive() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.clock.Now()
+	e.alive = now
+	e.failures = 0
+	e.health.Healthy = true
+	e.health.LastMeasurement = now
+}
+
+// stalled reports whether no measurements have been received within the stall timeout
+func (e *Engine) stalled() bool {
+	if e.recovery.StallTimeout <= 0 {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.clock.Now().Sub(e.alive) > e.recovery.StallTimeout
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+var errAdapter = errors.New("adapter failure")
+
+// failingBLEScanner fails the given number of scans before delivering the advertisements
+type failingBLEScanner struct {
+	mu       sync.Mutex
+	failures int
+	next     BLEScanner
+}
+
+func (m *failingBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	m.mu.Lock()
+	if m.failures > 0 {
+		m.failures--
+		m.mu.Unlock()
+		return errAdapter
+	}
+	m.mu.Unlock()
+	return m.next.Scan(ctx, allowDup, h, f)
+}
+
+type countingDeviceCreator struct {
+	mu       sync.Mutex
+	created  int
+	failures int
+}
+
+func (c *countingDeviceCreator) NewDevice(impl string) (ble.Device, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created++
+	if c.failures > 0 {
+		c.failures--
+		return nil, errAdapter
+	}
+	return mockDevice{}, nil
+}
+
+func (c *countingDeviceCreator) Created() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.created
+}
+
+func TestListenRecoversAfterScanFailure(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	exp := chanExporter{ch: make(chan sensor.Data)}
+	e.Exporters = []exporter.Exporter{exp}
+	e.meas.BLE = &failingBLEScanner{failures: 2, next: replayOf(testAdvertisement)}
+	dev := &countingDeviceCreator{}
+	e.dev = dev
+	clock := newFakeClock(cronTestStart)
+	e.clock = clock
+	e.SetRecovery(RecoveryConfig{InitialBackoff: time.Second, MaxBackoff: time.Minute})
+	require.NoError(t, e.Init("default"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Listen(ctx)
+		close(done)
+	}()
+	// The first recovery is immediate, the second one waits for the initial backoff
+	assert.Equal(t, cronTestStart.Add(time.Second), <-clock.waiting)
+	clock.Advance(time.Second)
+	assert.Equal(t, testAddr1, (<-exp.ch).Addr)
+	assert.Equal(t, 3, dev.Created())
+	health := e.Health()
+	assert.True(t, health.Healthy)
+	assert.Equal(t, 2, health.RecoveryAttempts)
+	assert.Equal(t, errAdapter.Error(), health.LastError)
+	cancel()
+	<-done
+}
+
+func TestRecoverBacksOffExponentially(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	dev := &countingDeviceCreator{}
+	e.dev = dev
+	clock := newFakeClock(cronTestStart)
+	e.clock = clock
+	e.SetRecovery(RecoveryConfig{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second})
+	require.NoError(t, e.Init("default"))
+	dev.failures = 3
+	done := make(chan bool)
+	go func() {
+		done <- e.recover(context.Background(), ErrStalled)
+	}()
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		deadline := <-clock.waiting
+		assert.Equal(t, clock.Now().Add(backoff), deadline)
+		clock.Advance(backoff)
+	}
+	assert.True(t, <-done)
+	assert.Equal(t, 5, dev.Created())
+	assert.Equal(t, 4, e.Health().RecoveryAttempts)
+	assert.False(t, e.Health().Healthy)
+}
+
+func TestRecoverStopsWhenContextDone(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	dev := &countingDeviceCreator{}
+	e.dev = dev
+	e.clock = newFakeClock(cronTestStart)
+	require.NoError(t, e.Init("default"))
+	dev.failures = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		done <- e.recover(ctx, errAdapter)
+	}()
+	<-e.clock.(*fakeClock).waiting
+	cancel()
+	assert.False(t, <-done)
+}
+
+func TestCollectRecoversStalledAdapter(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	e.meas.BLE = replayOf()
+	dev := &countingDeviceCreator{}
+	e.dev = dev
+	clock := newFakeClock(cronTestStart)
+	e.clock = clock
+	e.SetRecovery(RecoveryConfig{StallTimeout: time.Minute})
+	require.NoError(t, e.Init("default"))
+	e.Collect(context.Background())
+	assert.Equal(t, 1, dev.Created())
+	clock.Advance(2 * time.Minute)
+	e.Collect(context.Background())
+	assert.Equal(t, 2, dev.Created())
+	health := e.Health()
+	assert.Equal(t, 1, health.RecoveryAttempts)
+	assert.Equal(t, ErrStalled.Error(), health.LastError)
+}
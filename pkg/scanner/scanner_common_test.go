@@ -2,6 +2,8 @@ package scanner
 
 import (
 	"context"
+	"encoding/hex"
+	"time"
 
 	"github.com/go-ble/ble"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
@@ -66,32 +68,26 @@ func (m mockDeviceCreator) NewDevice(impl string) (ble.Device, error) {
 	return m.device, nil
 }
 
-type mockBLEScanner struct {
-	advertisements []ble.Advertisement
-	current        int
-}
+var replayStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 
-func NewMockBLEScanner(advertisements ...ble.Advertisement) *mockBLEScanner {
-	return &mockBLEScanner{
-		advertisements: advertisements,
-		current:        0,
+// replayOf creates a replayer that replays the advertisements one per scan
+func replayOf(advertisements ...ble.Advertisement) *Replayer {
+	records := make([]Record, len(advertisements))
+	for i, a := range advertisements {
+		records[i] = Record{
+			Timestamp:        replayStart.Add(time.Duration(i) * time.Hour),
+			Addr:             a.Addr().String(),
+			ManufacturerData: hex.EncodeToString(a.ManufacturerData()),
+			RSSI:             a.RSSI(),
+		}
 	}
-}
-
-func (m *mockBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
-	if m.current == len(m.advertisements) {
-		//m.current = 0
-		return nil
-	}
-	h(m.advertisements[m.current])
-	m.current++
-	<-ctx.Done()
-	return nil
+	return NewReplayerFromRecords(records, 1)
 }
 
 type mockAdvertisement struct {
 	manufacturerData []byte
 	addr             string
+	rssi             int
 }
 
 func (m mockAdvertisement) Addr() ble.Addr {
@@ -131,7 +127,7 @@ func (m mockAdvertisement) SolicitedService() []ble.UUID {
 }
 
 func (m mockAdvertisement) RSSI() int {
-	return 0
+	return m.rssi
 }
 
 func (m mockAdvertisement) Address() ble.Addr {
@@ -5,10 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-ble/ble"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
 )
 
 func TestScanContinuously(t *testing.T) {
@@ -17,23 +19,132 @@ func TestScanContinuously(t *testing.T) {
 	exp := new(mockExporter)
 	scn.Exporters = []exporter.Exporter{exp}
 	device := mockDevice{}
-	bleScanner := NewMockBLEScanner(testAdvertisement)
+	bleScanner := replayOf(testAdvertisement)
 	scn.meas.BLE = bleScanner
 	scn.dev = mockDeviceCreator{device: device}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	err := scn.Init("default")
 	require.NoError(t, err)
-	scn.Scan(ctx)
-	// Wait a bit for messages to appear in the measurements channel
-	time.Sleep(100 * time.Millisecond)
-	scn.Stop()
+	// The scan finishes once the replay has been exhausted
+	require.NoError(t, scn.Scan(ctx).Wait())
 	require.NotEmpty(t, exp.events)
 	e := exp.events[0]
 	assert.Equal(t, "Test", e.Name)
 	assert.Equal(t, testAddr1, e.Addr)
-	assert.Equal(t, 55.0, e.Temperature)
-	assert.Equal(t, 60.0, e.Humidity)
-	assert.Equal(t, 510.0, e.Pressure)
-	assert.Equal(t, 500.0, e.BatteryVoltage)
+	assert.Equal(t, 55.0, *e.Temperature)
+	assert.Equal(t, 60.0, *e.Humidity)
+	assert.Equal(t, 510.0, *e.Pressure)
+	assert.Equal(t, 500.0, *e.BatteryVoltage)
+}
+
+// feedBLEScanner delivers the advertisements sent to it until the context is done
+type feedBLEScanner chan ble.Advertisement
+
+func (m feedBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	for {
+		select {
+		case a := <-m:
+			if f == nil || f(a) {
+				h(a)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestScanContinuouslyReconfigure(t *testing.T) {
+	scn := NewContinuous(logger, peripherals)
+	exp1 := chanExporter{ch: make(chan sensor.Data, 4)}
+	scn.Exporters = []exporter.Exporter{exp1}
+	feed := make(feedBLEScanner)
+	scn.SetBLEScanner(feed)
+	require.NoError(t, scn.Init("default"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scn.Scan(ctx)
+	advertisement2 := mockAdvertisement{addr: testAddr2, manufacturerData: testData}
+	feed <- testAdvertisement
+	assert.Equal(t, "Test", (<-exp1.ch).Name)
+
+	exp2 := chanExporter{ch: make(chan sensor.Data, 4)}
+	scn.SetPeripherals(map[string]string{testAddr2: "Upstairs"}, nil, nil)
+	scn.SetExporters([]exporter.Exporter{exp2})
+	feed <- testAdvertisement
+	feed <- advertisement2
+	m := <-exp2.ch
+	assert.Equal(t, testAddr2, m.Addr, "removed peripherals are filtered out")
+	assert.Equal(t, "Upstairs", m.Name)
+	assert.Empty(t, exp1.ch, "replaced exporters receive no measurements")
+}
+
+// gateExporter blocks exports until released, regardless of the context
+type gateExporter struct {
+	release  chan struct{}
+	exported chan sensor.Data
+	closed   chan struct{}
+}
+
+func newGateExporter() *gateExporter {
+	return &gateExporter{
+		release:  make(chan struct{}),
+		exported: make(chan sensor.Data, 8),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (e *gateExporter) Name() string {
+	return "Gate"
+}
+
+func (e *gateExporter) Export(ctx context.Context, data sensor.Data) error {
+	<-e.release
+	e.exported <- data
+	return nil
+}
+
+func (e *gateExporter) Close() error {
+	close(e.closed)
+	return nil
+}
+
+func TestShutdownDrainsMeasurements(t *testing.T) {
+	scn := NewContinuous(logger, map[string]string{testAddr1: "Backyard", testAddr2: "Upstairs", testAddr3: "Downstairs"})
+	exp := newGateExporter()
+	scn.Exporters = []exporter.Exporter{exp}
+	feed := make(feedBLEScanner)
+	scn.SetBLEScanner(feed)
+	require.NoError(t, scn.Init("default"))
+	scn.Scan(context.Background())
+	for _, addr := range []string{testAddr1, testAddr2, testAddr3} {
+		feed <- mockAdvertisement{addr: addr, manufacturerData: testData}
+	}
+	done := make(chan error)
+	go func() {
+		done <- scn.Shutdown(context.Background())
+	}()
+	close(exp.release)
+	require.NoError(t, <-done)
+	assert.Len(t, exp.exported, 3, "received measurements are exported before shutting down")
+	_, open := <-exp.closed
+	assert.False(t, open, "exporters are closed")
+}
+
+func TestShutdownGracePeriodExpires(t *testing.T) {
+	scn := NewContinuous(logger, peripherals)
+	exp := newGateExporter()
+	defer close(exp.release)
+	scn.Exporters = []exporter.Exporter{exp}
+	feed := make(feedBLEScanner)
+	scn.SetBLEScanner(feed)
+	require.NoError(t, scn.Init("default"))
+	scn.Scan(context.Background())
+	feed <- testAdvertisement
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := scn.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, open := <-exp.closed
+	assert.False(t, open, "exporters are closed after the grace period")
 }
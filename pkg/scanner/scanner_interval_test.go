@@ -1,9 +1,7 @@
 package scanner
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"io"
 	"log/slog"
 	"testing"
@@ -12,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/niktheblak/ruuvitag-gollector/pkg/aggregate"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
 )
@@ -23,18 +22,12 @@ const (
 )
 
 var (
-	testData = sensor.DataFormat3{
-		ManufacturerID:      0x9904,
-		DataFormat:          3,
-		Humidity:            120,
-		Temperature:         55,
-		TemperatureFraction: 0,
-		Pressure:            1000,
-		AccelerationX:       0,
-		AccelerationY:       0,
-		AccelerationZ:       0,
-		BatteryVoltageMv:    500,
-	}
+	testData = sensor.EncodeSensorFormat3(sensor.Data{
+		Temperature:    sensor.Float64(55.0),
+		Humidity:       sensor.Float64(60.0),
+		Pressure:       sensor.Float64(510.0),
+		BatteryVoltage: sensor.Float64(500.0),
+	})
 	peripherals = map[string]string{
 		testAddr1: "Test",
 	}
@@ -44,13 +37,9 @@ var (
 
 func init() {
 	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.BigEndian, testData); err != nil {
-		panic(err)
-	}
 	testAdvertisement = mockAdvertisement{
 		addr:             testAddr1,
-		manufacturerData: buf.Bytes(),
+		manufacturerData: testData,
 	}
 }
 
@@ -65,25 +54,21 @@ func TestScanWithInterval(t *testing.T) {
 	}
 	scn := NewInterval(logger, peripherals)
 	defer scn.Close()
-	exp := new(mockExporter)
+	exp := chanExporter{ch: make(chan sensor.Data, 3)}
 	scn.Exporters = []exporter.Exporter{exp}
 	device := mockDevice{}
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.BigEndian, testData); err != nil {
-		panic(err)
-	}
-	scn.meas.BLE = NewMockBLEScanner(
+	scn.meas.BLE = replayOf(
 		mockAdvertisement{
 			addr:             testAddr1,
-			manufacturerData: buf.Bytes(),
+			manufacturerData: testData,
 		},
 		mockAdvertisement{
 			addr:             testAddr2,
-			manufacturerData: buf.Bytes(),
+			manufacturerData: testData,
 		},
 		mockAdvertisement{
 			addr:             testAddr3,
-			manufacturerData: buf.Bytes(),
+			manufacturerData: testData,
 		},
 	)
 	scn.dev = mockDeviceCreator{device: device}
@@ -91,16 +76,37 @@ func TestScanWithInterval(t *testing.T) {
 	defer cancel()
 	err := scn.Init("default")
 	require.NoError(t, err)
-	scn.Scan(ctx, 100*time.Millisecond)
-	// Wait a bit for messages to appear in the measurements channel
-	time.Sleep(2 * time.Second)
+	running := scn.Scan(ctx, 100*time.Millisecond)
+	// Each interval replays one advertisement
+	var events []sensor.Data
+	for i := 0; i < 3; i++ {
+		events = append(events, <-exp.ch)
+	}
 	scn.Stop()
-	require.Len(t, exp.events, 3)
-	e := exp.events[0]
+	require.NoError(t, running.Wait())
+	e := events[0]
 	assert.Equal(t, "Backyard", e.Name)
 	assert.Equal(t, testAddr1, e.Addr)
-	assert.Equal(t, 55.0, e.Temperature)
-	assert.Equal(t, 60.0, e.Humidity)
-	assert.Equal(t, 510.0, e.Pressure)
-	assert.Equal(t, 500.0, e.BatteryVoltage)
+	assert.Equal(t, 55.0, *e.Temperature)
+	assert.Equal(t, 60.0, *e.Humidity)
+	assert.Equal(t, 510.0, *e.Pressure)
+	assert.Equal(t, 500.0, *e.BatteryVoltage)
+}
+
+func TestAggregateInterval(t *testing.T) {
+	scn := NewInterval(logger, peripherals)
+	exp := new(mockExporter)
+	scn.Exporters = []exporter.Exporter{exp}
+	scn.SetAggregation([]aggregate.Func{aggregate.Min, aggregate.Max})
+	measurements := make(chan sensor.Data, 2)
+	measurements <- sensor.Data{Addr: testAddr1, Temperature: sensor.Float64(20.0)}
+	measurements <- sensor.Data{Addr: testAddr1, Temperature: sensor.Float64(24.0)}
+	close(measurements)
+	scn.doAggregate(context.Background(), measurements)
+	require.Len(t, exp.events, 2)
+	assert.Equal(t, "min", exp.events[0].Aggregate)
+	assert.Equal(t, 20.0, *exp.events[0].Temperature)
+	assert.Equal(t, "max", exp.events[1].Aggregate)
+	assert.Equal(t, 24.0, *exp.events[1].Temperature)
+	assert.Equal(t, 2, *exp.events[1].SampleCount)
 }
@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/cron"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/evenminutes"
+)
+
+// DefaultCronScanDuration is the default duration of scans scheduled with a cron expression
+const DefaultCronScanDuration = 30 * time.Second
+
+// Schedule decides when an Engine scans for measurements
+type Schedule interface {
+	// Run scans with the engine until the schedule is finished or the context is done
+	Run(ctx context.Context, e *Engine) error
+}
+
+// ScheduleFunc is a custom schedule
+type ScheduleFunc func(ctx context.Context, e *Engine) error
+
+func (f ScheduleFunc) Run(ctx context.Context, e *Engine) error {
+	return f(ctx, e)
+}
+
+// OnceSchedule collects measurements from all peripherals once
+type OnceSchedule struct{}
+
+func (OnceSchedule) Run(ctx context.Context, e *Engine) error {
+	e.Collect(ctx)
+	return nil
+}
+
+// IntervalSchedule collects measurements at fixed intervals aligned to even multiples of the interval.
+// With motion detection enabled, the engine keeps scanning for moving tags between collections.
+type IntervalSchedule struct {
+	Interval time.Duration
+}
+
+func (s IntervalSchedule) Run(ctx context.Context, e *Engine) error {
+	delay := evenminutes.Until(time.Now(), s.Interval)
+	e.logger.LogAttrs(ctx, slog.LevelInfo, "Sleeping until", slog.Time("time", time.Now().Add(delay)))
+	if !e.idle(ctx, time.After(delay)) {
+		return nil
+	}
+	e.logger.LogAttrs(ctx, slog.LevelInfo, "Scanning measurements", slog.Duration("interval", s.Interval))
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		scanCtx, cancel := context.WithTimeout(ctx, s.Interval)
+		e.Collect(scanCtx)
+		cancel()
+		if !e.idle(ctx, ticker.C) {
+			return nil
+		}
+	}
+}
+
+// ContinuousSchedule exports measurements immediately as they are received
+type ContinuousSchedule struct{}
+
+func (ContinuousSchedule) Run(ctx context.Context, e *Engine) error {
+	e.logger.Info("Listening for measurements")
+	e.Listen(ctx)
+	return nil
+}
+
+// CronSchedule collects measurements at the times given by a cron expression. With motion detection
+// enabled, the engine keeps scanning for moving tags between scans.
+type CronSchedule struct {
+	Expression *cron.Expression
+	// Duration is how long each scan listens for measurements
+	Duration time.Duration
+	// Jitter delays each scan by a random duration up to Jitter
+	Jitter time.Duration
+	// SkipIfRunning skips a scan if the previous one is still running. Otherwise the scan starts as soon as
+	// the previous one has finished.
+	SkipIfRunning bool
+	// Clock is the clock used for scheduling. Defaults to the wall clock.
+	Clock Clock
+	// Rand is the source of jitter. Defaults to the global random source.
+	Rand *rand.Rand
+}
+
+func (s CronSchedule) Run(ctx context.Context, e *Engine) error {
+	clock := s.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	duration := s.Duration
+	if duration <= 0 {
+		duration = DefaultCronScanDuration
+	}
+	running := make(chan struct{}, 1)
+	// finished is closed when the latest scan has finished
+	finished := make(chan struct{})
+	close(finished)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		now := clock.Now()
+		next := s.Expression.Next(now)
+		if next.IsZero() {
+			return fmt.Errorf("cron expression has no future scan times")
+		}
+		delay := next.Sub(now) + s.jitter()
+		e.logger.LogAttrs(ctx, slog.LevelInfo, "Sleeping until", slog.Time("time", now.Add(delay)))
+		if !s.sleep(ctx, e, clock.After(delay), finished) {
+			return nil
+		}
+		select {
+		case running <- struct{}{}:
+		default:
+			if s.SkipIfRunning {
+				e.logger.LogAttrs(ctx, slog.LevelWarn, "Skipping scan because the previous scan is still running")
+				continue
+			}
+			select {
+			case running <- struct{}{}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		done := make(chan struct{})
+		finished = done
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done)
+			defer func() { <-running }()
+			scanCtx, cancel := context.WithTimeout(ctx, duration)
+			defer cancel()
+			e.Collect(scanCtx)
+		}()
+	}
+}
+
+// sleep waits until next fires and reports whether it did. Motion is watched only after the running scan
+// has finished so that the engine never scans twice at the same time.
+func (s CronSchedule) sleep(ctx context.Context, e *Engine, next <-chan time.Time, finished <-chan struct{}) bool {
+	select {
+	case <-next:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-finished:
+	}
+	return e.idle(ctx, next)
+}
+
+func (s CronSchedule) jitter() time.Duration {
+	if s.Jitter <= 0 {
+		return 0
+	}
+	if s.Rand != nil {
+		return time.Duration(s.Rand.Int63n(int64(s.Jitter)))
+	}
+	return time.Duration(rand.Int63n(int64(s.Jitter)))
+}
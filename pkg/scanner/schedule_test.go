@@ -0,0 +1,221 @@
+package scanner
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/cron"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func TestCustomSchedule(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	exp := new(mockExporter)
+	e.Exporters = []exporter.Exporter{exp}
+	e.meas.BLE = replayOf(testAdvertisement, testAdvertisement)
+	rounds := 0
+	err := e.Run(context.Background(), ScheduleFunc(func(ctx context.Context, e *Engine) error {
+		for i := 0; i < 2; i++ {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			e.Collect(ctx)
+			cancel()
+			rounds++
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, rounds)
+	assert.Len(t, exp.events, 2)
+}
+
+func TestRunStopsWhenStopped(t *testing.T) {
+	e := NewEngine(logger, peripherals)
+	e.meas.BLE = replayOf()
+	done := make(chan error)
+	go func() {
+		done <- e.Run(context.Background(), ContinuousSchedule{})
+	}()
+	e.Stop()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("engine did not stop")
+	}
+}
+
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []fakeTimer
+	waiting chan time.Time
+}
+
+type fakeTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{
+		now:     now,
+		waiting: make(chan time.Time, 16),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time when the clock is advanced past the deadline. The deadline
+// is sent to the waiting channel so that tests know when the code under test is waiting.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	deadline := c.now.Add(d)
+	c.timers = append(c.timers, fakeTimer{deadline: deadline, ch: ch})
+	c.mu.Unlock()
+	c.waiting <- deadline
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var pending []fakeTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.ch <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+type chanExporter struct {
+	exporter.NoOp
+	ch chan sensor.Data
+}
+
+func (e chanExporter) Export(ctx context.Context, data sensor.Data) error {
+	e.ch <- data
+	return nil
+}
+
+type blockingBLEScanner struct {
+	started chan struct{}
+}
+
+func (m blockingBLEScanner) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler, f ble.AdvFilter) error {
+	m.started <- struct{}{}
+	<-ctx.Done()
+	return nil
+}
+
+var cronTestStart = time.Date(2024, 1, 1, 12, 0, 3, 0, time.UTC)
+
+func TestCronSchedule(t *testing.T) {
+	expr, err := cron.Parse("TZ=UTC */10 * * * * *")
+	require.NoError(t, err)
+	e := NewEngine(logger, peripherals)
+	exp := chanExporter{ch: make(chan sensor.Data)}
+	e.Exporters = []exporter.Exporter{exp}
+	e.meas.BLE = replayOf(testAdvertisement, testAdvertisement)
+	clock := newFakeClock(cronTestStart)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx, CronSchedule{Expression: expr, Duration: time.Minute, Clock: clock})
+	}()
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC), <-clock.waiting)
+	clock.Advance(7 * time.Second)
+	assert.Equal(t, testAddr1, (<-exp.ch).Addr)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 20, 0, time.UTC), <-clock.waiting)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, testAddr1, (<-exp.ch).Addr)
+	<-clock.waiting
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestCronScheduleSkipIfRunning(t *testing.T) {
+	expr, err := cron.Parse("TZ=UTC */10 * * * * *")
+	require.NoError(t, err)
+	e := NewEngine(logger, map[string]string{testAddr1: "Backyard", testAddr2: "Upstairs"})
+	bleScanner := blockingBLEScanner{started: make(chan struct{}, 2)}
+	e.meas.BLE = bleScanner
+	clock := newFakeClock(cronTestStart)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx, CronSchedule{Expression: expr, Duration: time.Hour, SkipIfRunning: true, Clock: clock})
+	}()
+	<-clock.waiting
+	clock.Advance(7 * time.Second)
+	<-bleScanner.started
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 20, 0, time.UTC), <-clock.waiting)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC), <-clock.waiting)
+	select {
+	case <-bleScanner.started:
+		t.Fatal("scan started while the previous scan was running")
+	default:
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestCronScheduleWatchesMotion(t *testing.T) {
+	expr, err := cron.Parse("TZ=UTC 0 0 * * * *")
+	require.NoError(t, err)
+	e := NewEngine(logger, peripherals)
+	exp := chanEventExporter{chanExporter: chanExporter{ch: make(chan sensor.Data)}, events: make(chan exporter.Event)}
+	e.Exporters = []exporter.Exporter{exp}
+	feed := make(feedBLEScanner)
+	e.SetBLEScanner(feed)
+	e.SetMotionDetector(NewMotionDetector(&MotionConfig{MovementThreshold: 1}, nil))
+	clock := newFakeClock(cronTestStart)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx, CronSchedule{Expression: expr, Clock: clock})
+	}()
+	assert.Equal(t, time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC), <-clock.waiting)
+	for _, counter := range []int{1, 2} {
+		feed <- mockAdvertisement{addr: testAddr1, manufacturerData: sensor.EncodeSensorFormat5(sensor.Data{MovementCounter: sensor.Int(counter)})}
+	}
+	event := <-exp.events
+	assert.Equal(t, exporter.Motion, event.Type)
+	assert.Equal(t, 2, *(<-exp.ch).MovementCounter)
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestCronScheduleJitter(t *testing.T) {
+	expr, err := cron.Parse("TZ=UTC */10 * * * * *")
+	require.NoError(t, err)
+	e := NewEngine(logger, peripherals)
+	clock := newFakeClock(cronTestStart)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx, CronSchedule{Expression: expr, Jitter: 5 * time.Second, Clock: clock, Rand: rand.New(rand.NewSource(1))})
+	}()
+	deadline := <-clock.waiting
+	assert.False(t, deadline.Before(time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)))
+	assert.True(t, deadline.Before(time.Date(2024, 1, 1, 12, 0, 15, 0, time.UTC)))
+	cancel()
+	require.NoError(t, <-done)
+}
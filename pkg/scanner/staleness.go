@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+)
+
+// StalenessTracker tracks when measurements were last received from each configured peripheral and
+// reports peripherals that go missing and recover.
+// StalenessTracker is safe for concurrent use.
+type StalenessTracker struct {
+	threshold   time.Duration
+	peripherals map[string]string
+	started     time.Time
+	mu          sync.Mutex
+	lastSeen    map[string]time.Time
+	missing     map[string]bool
+	// added records when peripherals added after tracking started were added
+	added map[string]time.Time
+}
+
+// NewStalenessTracker creates a tracker that considers a peripheral missing if no measurements have been
+// received from it within the threshold. Tracking starts from the given time.
+func NewStalenessTracker(peripherals map[string]string, threshold time.Duration, started time.Time) *StalenessTracker {
+	return &StalenessTracker{
+		threshold:   threshold,
+		peripherals: peripherals,
+		started:     started,
+		lastSeen:    make(map[string]time.Time),
+		missing:     make(map[string]bool),
+		added:       make(map[string]time.Time),
+	}
+}
+
+// SetPeripherals replaces the tracked peripherals. Peripherals that are no longer tracked are forgotten
+// and new peripherals are tracked from the given time.
+func (t *StalenessTracker) SetPeripherals(peripherals map[string]string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for addr := range t.peripherals {
+		if _, ok := peripherals[addr]; !ok {
+			delete(t.lastSeen, addr)
+			delete(t.missing, addr)
+			delete(t.added, addr)
+		}
+	}
+	for addr := range peripherals {
+		if _, ok := t.peripherals[addr]; !ok {
+			t.added[addr] = now
+		}
+	}
+	t.peripherals = peripherals
+}
+
+// Seen records a measurement from the peripheral. If the peripheral was missing, a recovery event is returned.
+func (t *StalenessTracker) Seen(addr string, ts time.Time) (exporter.Event, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.peripherals[addr]; !ok {
+		return exporter.Event{}, false
+	}
+	lastSeen := t.lastSeen[addr]
+	if ts.After(lastSeen) {
+		t.lastSeen[addr] = ts
+	}
+	if !t.missing[addr] {
+		return exporter.Event{}, false
+	}
+	delete(t.missing, addr)
+	return exporter.Event{
+		Type:      exporter.TagRecovered,
+		Addr:      addr,
+		Name:      t.peripherals[addr],
+		LastSeen:  lastSeen,
+		Timestamp: ts,
+	}, true
+}
+
+// Check returns an event for each peripheral that has gone missing since the previous check
+func (t *StalenessTracker) Check(now time.Time) []exporter.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var events []exporter.Event
+	for addr, name := range t.peripherals {
+		if t.missing[addr] {
+			continue
+		}
+		lastSeen, seen := t.lastSeen[addr]
+		since := lastSeen
+		if !seen {
+			since = t.started
+			if added, ok := t.added[addr]; ok {
+				since = added
+			}
+		}
+		if now.Sub(since) < t.threshold {
+			continue
+		}
+		t.missing[addr] = true
+		events = append(events, exporter.Event{
+			Type:      exporter.TagMissing,
+			Addr:      addr,
+			Name:      name,
+			LastSeen:  lastSeen,
+			Timestamp: now,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Addr < events[j].Addr
+	})
+	return events
+}
+
+// exportEvent delivers the event to each exporter that supports events
+func exportEvent(ctx context.Context, logger *slog.Logger, exporters []exporter.Exporter, event exporter.Event) {
+	logger.LogAttrs(ctx, slog.LevelInfo, "Exporting event", slog.String("type", string(event.Type)), slog.String("addr", event.Addr), slog.String("name", event.Name))
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	for _, e := range exporters {
+		ee, ok := exporter.AsEventExporter(e)
+		if !ok {
+			continue
+		}
+		if err := ee.ExportEvent(ctx, event); err != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "Failed to export event", slog.String("exporter", e.Name()), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/niktheblak/ruuvitag-gollector/pkg/exporter"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/outlier"
+	"github.com/niktheblak/ruuvitag-gollector/pkg/sensor"
+)
+
+func TestStalenessTracker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewStalenessTracker(map[string]string{
+		testAddr1: "Backyard",
+		testAddr2: "Upstairs",
+	}, time.Minute, start)
+	_, ok := tracker.Seen(testAddr1, start.Add(10*time.Second))
+	assert.False(t, ok)
+	_, ok = tracker.Seen(testAddr3, start.Add(10*time.Second))
+	assert.False(t, ok, "unknown peripherals are not tracked")
+	assert.Empty(t, tracker.Check(start.Add(30*time.Second)))
+
+	events := tracker.Check(start.Add(65 * time.Second))
+	require.Len(t, events, 1)
+	assert.Equal(t, exporter.TagMissing, events[0].Type)
+	assert.Equal(t, testAddr2, events[0].Addr)
+	assert.Equal(t, "Upstairs", events[0].Name)
+	assert.True(t, events[0].LastSeen.IsZero())
+
+	events = tracker.Check(start.Add(75 * time.Second))
+	require.Len(t, events, 1)
+	assert.Equal(t, testAddr1, events[0].Addr)
+	assert.Equal(t, start.Add(10*time.Second), events[0].LastSeen)
+	assert.Empty(t, tracker.Check(start.Add(80*time.Second)), "missing peripherals are reported once")
+
+	event, ok := tracker.Seen(testAddr1, start.Add(90*time.Second))
+	require.True(t, ok)
+	assert.Equal(t, exporter.TagRecovered, event.Type)
+	assert.Equal(t, "Backyard", event.Name)
+	assert.Equal(t, start.Add(10*time.Second), event.LastSeen)
+	_, ok = tracker.Seen(testAddr1, start.Add(91*time.Second))
+	assert.False(t, ok)
+}
+
+func TestStalenessTrackerSetPeripherals(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewStalenessTracker(map[string]string{
+		testAddr1: "Backyard",
+		testAddr2: "Upstairs",
+	}, time.Minute, start)
+	require.Len(t, tracker.Check(start.Add(65*time.Second)), 2)
+
+	tracker.SetPeripherals(map[string]string{
+		testAddr1: "Backyard",
+		testAddr3: "Downstairs",
+	}, start.Add(70*time.Second))
+	assert.Empty(t, tracker.Check(start.Add(100*time.Second)), "added peripherals are tracked from when they were added")
+	events := tracker.Check(start.Add(135 * time.Second))
+	require.Len(t, events, 1)
+	assert.Equal(t, testAddr3, events[0].Addr)
+	_, ok := tracker.Seen(testAddr2, start.Add(140*time.Second))
+	assert.False(t, ok, "removed peripherals are not tracked")
+	event, ok := tracker.Seen(testAddr1, start.Add(140*time.Second))
+	require.True(t, ok, "retained peripherals keep their state")
+	assert.Equal(t, exporter.TagRecovered, event.Type)
+}
+
+type mockEventExporter struct {
+	mockExporter
+	events []exporter.Event
+}
+
+func (m *mockEventExporter) ExportEvent(ctx context.Context, event exporter.Event) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestIntervalScannerExportsEvents(t *testing.T) {
+	scn := NewInterval(logger, peripherals)
+	exp := new(mockEventExporter)
+	scn.Exporters = []exporter.Exporter{exporter.WithoutDiagnostics(exp), new(mockExporter)}
+	scn.staleness = NewStalenessTracker(peripherals, time.Minute, time.Now().Add(-2*time.Minute))
+	scn.checkStaleness(context.Background())
+	require.Len(t, exp.events, 1)
+	assert.Equal(t, exporter.TagMissing, exp.events[0].Type)
+	require.NoError(t, scn.export(context.Background(), sensor.Data{Addr: testAddr1, Timestamp: time.Now()}))
+	require.Len(t, exp.events, 2)
+	assert.Equal(t, exporter.TagRecovered, exp.events[1].Type)
+}
+
+func TestStalenessUsesEngineClock(t *testing.T) {
+	e := NewEngine(logger, map[string]string{testAddr1: "Backyard"})
+	clock := newFakeClock(cronTestStart)
+	e.clock = clock
+	exp := new(mockEventExporter)
+	e.Exporters = []exporter.Exporter{exp}
+	e.SetStalenessThreshold(time.Minute)
+	f, err := outlier.New([]outlier.Rule{{Field: "temperature", Method: outlier.Rate, Threshold: 6}}, nil)
+	require.NoError(t, err)
+	e.SetOutlierFilter(f)
+	ctx := context.Background()
+	// Replayed measurements are timestamped long before the engine clock
+	require.NoError(t, e.export(ctx, sensor.Data{Addr: testAddr1, Temperature: sensor.Float64(20), Timestamp: replayStart}))
+	clock.Advance(50 * time.Second)
+	require.NoError(t, e.export(ctx, sensor.Data{Addr: testAddr1, Temperature: sensor.Float64(84), Timestamp: replayStart.Add(time.Second)}))
+	assert.Equal(t, 1, e.Health().RejectedMeasurements)
+	clock.Advance(50 * time.Second)
+	e.checkStaleness(ctx)
+	assert.Empty(t, exp.events, "rejected measurements count as seen")
+	clock.Advance(time.Minute)
+	e.checkStaleness(ctx)
+	require.Len(t, exp.events, 1)
+	assert.Equal(t, exporter.TagMissing, exp.events[0].Type)
+	assert.Equal(t, cronTestStart.Add(50*time.Second), exp.events[0].LastSeen)
+}
@@ -4,19 +4,58 @@ import (
 	"time"
 )
 
+// Data contains the readings of a single RuuviTag advertisement.
+// Readings that the sensor reported as invalid or that the data format does not contain are nil.
 type Data struct {
-	Addr              string    `json:"mac"`
-	Name              string    `json:"name"`
-	Temperature       float64   `json:"temperature"`
-	Humidity          float64   `json:"humidity"`
-	DewPoint          float64   `json:"dew_point,omitempty"`
-	Pressure          float64   `json:"pressure"`
-	BatteryVoltage    float64   `json:"battery_voltage,omitempty"`
-	TxPower           int       `json:"tx_power,omitempty"`
-	AccelerationX     int       `json:"acceleration_x"`
-	AccelerationY     int       `json:"acceleration_y"`
-	AccelerationZ     int       `json:"acceleration_z"`
-	MovementCounter   int       `json:"movement_counter"`
-	MeasurementNumber int       `json:"measurement_number"`
-	Timestamp         time.Time `json:"ts"`
+	Addr                 string    `json:"mac"`
+	Name                 string    `json:"name"`
+	Temperature          *float64  `json:"temperature,omitempty"`
+	Humidity             *float64  `json:"humidity,omitempty"`
+	DewPoint             *float64  `json:"dew_point,omitempty"`
+	Pressure             *float64  `json:"pressure,omitempty"`
+	BatteryVoltage       *float64  `json:"battery_voltage,omitempty"`
+	TxPower              *int      `json:"tx_power,omitempty"`
+	AccelerationX        *int      `json:"acceleration_x,omitempty"`
+	AccelerationY        *int      `json:"acceleration_y,omitempty"`
+	AccelerationZ        *int      `json:"acceleration_z,omitempty"`
+	MovementCounter      *int      `json:"movement_counter,omitempty"`
+	MeasurementNumber    *int      `json:"measurement_number,omitempty"`
+	PM1                  *float64  `json:"pm1_0,omitempty"`
+	PM25                 *float64  `json:"pm2_5,omitempty"`
+	PM4                  *float64  `json:"pm4_0,omitempty"`
+	PM10                 *float64  `json:"pm10_0,omitempty"`
+	CO2                  *int      `json:"co2,omitempty"`
+	VOC                  *int      `json:"voc,omitempty"`
+	NOX                  *int      `json:"nox,omitempty"`
+	Luminosity           *float64  `json:"luminosity,omitempty"`
+	SoundInstant         *float64  `json:"sound_instant,omitempty"`
+	SoundAverage         *float64  `json:"sound_average,omitempty"`
+	SoundPeak            *float64  `json:"sound_peak,omitempty"`
+	AbsoluteHumidity     *float64  `json:"absolute_humidity,omitempty"`
+	VaporPressureDeficit *float64  `json:"vapor_pressure_deficit,omitempty"`
+	HumidityRatio        *float64  `json:"humidity_ratio,omitempty"`
+	WetBulb              *float64  `json:"wet_bulb,omitempty"`
+	FrostPoint           *float64  `json:"frost_point,omitempty"`
+	HeatIndex            *float64  `json:"heat_index,omitempty"`
+	AirDensity           *float64  `json:"air_density,omitempty"`
+	RawTemperature       *float64  `json:"raw_temperature,omitempty"`
+	RawHumidity          *float64  `json:"raw_humidity,omitempty"`
+	RawPressure          *float64  `json:"raw_pressure,omitempty"`
+	RSSI                 *int      `json:"rssi,omitempty"`
+	RawData              string    `json:"raw_data,omitempty"`
+	AddressType          string    `json:"address_type,omitempty"`
+	Adapter              string    `json:"adapter,omitempty"`
+	Aggregate            string    `json:"aggregate,omitempty"`
+	SampleCount          *int      `json:"sample_count,omitempty"`
+	Timestamp            time.Time `json:"ts"`
+}
+
+// Float64 returns a pointer to the given value for use in the optional fields of Data
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to the given value for use in the optional fields of Data
+func Int(v int) *int {
+	return &v
 }
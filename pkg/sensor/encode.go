@@ -0,0 +1,53 @@
+package sensor
+
+import (
+	"fmt"
+	"math"
+	"net"
+)
+
+// Encode encodes the sensor data to RuuviTag manufacturer data in the given data format.
+// Only the unencrypted legacy formats 3 and 5 are supported.
+func Encode(sd Data, format byte) ([]byte, error) {
+	switch format {
+	case 3:
+		return EncodeSensorFormat3(sd), nil
+	case 5:
+		return EncodeSensorFormat5(sd), nil
+	default:
+		return nil, fmt.Errorf("encoding sensor format %v is not supported", format)
+	}
+}
+
+// macAddress returns the MAC address of the sensor data as bytes or zeros if the address is not a valid MAC address
+func macAddress(addr string) []byte {
+	mac, err := net.ParseMAC(addr)
+	if err != nil || len(mac) != 6 {
+		return make([]byte, 6)
+	}
+	return mac
+}
+
+// scale multiplies the value by factor and rounds it to the nearest integer. The result is
+// ok only if it is within [min, max].
+func scale(v, factor float64, min, max int) (int, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	r := math.Round(v * factor)
+	if r < float64(min) || r > float64(max) {
+		return 0, false
+	}
+	return int(r), true
+}
+
+// clamp returns v limited to [min, max]
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,143 @@
+package sensor
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRAWv2Data(t *testing.T) {
+	sd, err := Parse(testData)
+	require.NoError(t, err)
+	sd.Addr = "f7:fa:74:4a:1e:1a"
+	data, err := Encode(sd, 5)
+	require.NoError(t, err)
+	assert.Equal(t, testData[:26], data)
+}
+
+func TestEncodeRAWv2InvalidData(t *testing.T) {
+	data, err := Encode(Data{}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "9904058000FFFFFFFF800080008000FFFFFFFFFF000000000000", strings.ToUpper(hex.EncodeToString(data)))
+}
+
+func TestEncodeRAWv2OutOfRange(t *testing.T) {
+	sd := Data{
+		Temperature:       Float64(200.0),
+		Humidity:          Float64(-1.0),
+		Pressure:          Float64(1200.0),
+		BatteryVoltage:    Float64(1.0),
+		TxPower:           Int(-50),
+		AccelerationX:     Int(-40000),
+		MovementCounter:   Int(255),
+		MeasurementNumber: Int(65535),
+	}
+	parsed, err := Parse(EncodeSensorFormat5(sd))
+	require.NoError(t, err)
+	assert.Nil(t, parsed.Temperature)
+	assert.Nil(t, parsed.Humidity)
+	assert.Nil(t, parsed.Pressure)
+	assert.Nil(t, parsed.BatteryVoltage)
+	assert.Nil(t, parsed.TxPower)
+	assert.Nil(t, parsed.AccelerationX)
+	assert.Nil(t, parsed.MovementCounter)
+	assert.Nil(t, parsed.MeasurementNumber)
+}
+
+func TestEncodeRAWv2RoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		sd := Data{
+			Temperature:       Float64(rnd.Float64()*320.0 - 160.0),
+			Humidity:          Float64(rnd.Float64() * 100.0),
+			Pressure:          Float64(rnd.Float64()*655.0 + 500.0),
+			BatteryVoltage:    Float64(rnd.Float64()*2.0 + 1.6),
+			TxPower:           Int(rnd.Intn(31)*2 - 40),
+			AccelerationX:     Int(rnd.Intn(65535) - 32767),
+			AccelerationY:     Int(rnd.Intn(65535) - 32767),
+			AccelerationZ:     Int(rnd.Intn(65535) - 32767),
+			MovementCounter:   Int(rnd.Intn(255)),
+			MeasurementNumber: Int(rnd.Intn(65535)),
+		}
+		parsed, err := Parse(EncodeSensorFormat5(sd))
+		require.NoError(t, err)
+		assertFloatPtr(t, sd.Temperature, parsed.Temperature, 0.0025)
+		assertFloatPtr(t, sd.Humidity, parsed.Humidity, 0.00125)
+		assertFloatPtr(t, sd.Pressure, parsed.Pressure, 0.005)
+		assertFloatPtr(t, sd.BatteryVoltage, parsed.BatteryVoltage, 0.0005)
+		assert.Equal(t, sd.TxPower, parsed.TxPower)
+		assert.Equal(t, sd.AccelerationX, parsed.AccelerationX)
+		assert.Equal(t, sd.AccelerationY, parsed.AccelerationY)
+		assert.Equal(t, sd.AccelerationZ, parsed.AccelerationZ)
+		assert.Equal(t, sd.MovementCounter, parsed.MovementCounter)
+		assert.Equal(t, sd.MeasurementNumber, parsed.MeasurementNumber)
+	}
+}
+
+func TestEncodeRAWv1RoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		sd := Data{
+			Temperature:    Float64(rnd.Float64()*255.0 - 127.5),
+			Humidity:       Float64(rnd.Float64() * 100.0),
+			Pressure:       Float64(rnd.Float64()*655.0 + 500.0),
+			BatteryVoltage: Float64(float64(rnd.Intn(3600))),
+			AccelerationX:  Int(rnd.Intn(65536) - 32768),
+			AccelerationY:  Int(rnd.Intn(65536) - 32768),
+			AccelerationZ:  Int(rnd.Intn(65536) - 32768),
+		}
+		data, err := Encode(sd, 3)
+		require.NoError(t, err)
+		parsed, err := Parse(data)
+		require.NoError(t, err)
+		assertFloatPtr(t, sd.Temperature, parsed.Temperature, 0.005)
+		assertFloatPtr(t, sd.Humidity, parsed.Humidity, 0.25)
+		assertFloatPtr(t, sd.Pressure, parsed.Pressure, 0.005)
+		assert.Equal(t, sd.BatteryVoltage, parsed.BatteryVoltage)
+		assert.Equal(t, sd.AccelerationX, parsed.AccelerationX)
+		assert.Equal(t, sd.AccelerationY, parsed.AccelerationY)
+		assert.Equal(t, sd.AccelerationZ, parsed.AccelerationZ)
+	}
+}
+
+func TestEncodeTemperature(t *testing.T) {
+	for _, temp := range []float64{0.0, -2.0, 2.0, 2.2, -2.99, 127.99, -127.99} {
+		assert.InDelta(t, temp, ParseTemperature(EncodeTemperature(temp)), 0.001)
+	}
+	assert.Equal(t, 127.99, ParseTemperature(EncodeTemperature(300.0)))
+}
+
+func TestEncodeUnsupportedFormat(t *testing.T) {
+	_, err := Encode(Data{}, 8)
+	assert.Error(t, err)
+}
+
+// FuzzParse checks that parsing arbitrary data does not panic and that encoding parsed legacy
+// format data is stable: once parsed values are encoded, parsing and encoding them again yields
+// the same bytes.
+func FuzzParse(f *testing.F) {
+	f.Add(testData)
+	f.Add(EncodeSensorFormat3(Data{Temperature: Float64(-12.5), Humidity: Float64(40.0)}))
+	f.Add(EncodeSensorFormat5(Data{}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sd, err := Parse(data)
+		if err != nil {
+			return
+		}
+		format := data[2]
+		if format != 3 && format != 5 {
+			return
+		}
+		encoded, err := Encode(sd, format)
+		require.NoError(t, err)
+		reparsed, err := Parse(encoded)
+		require.NoError(t, err)
+		reencoded, err := Encode(reparsed, format)
+		require.NoError(t, err)
+		assert.Equal(t, encoded, reencoded)
+	})
+}
@@ -0,0 +1,87 @@
+package sensor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+/*
+DataFormat6 is the payload of data format 6 (Ruuvi Air):
+
+	Byte    Value Range			Explanation
+	---------------------------------------
+	0 		06 					Format type code
+	1–2 	-163.835 — 163.835 	Temperature (16bit signed in .005 centigrade). 0x8000 indicates invalid.
+	3–4 	0 — 100				Humidity (16bit unsigned in .0025%). 0xFFFF indicates invalid.
+	5–6 	500 — 1155.34 		Atmospheric pressure (16bit unsigned, offset -50000 Pa). 0xFFFF indicates invalid.
+	7–8 	0 — 1000 			PM2.5 (16bit unsigned in 0.1 µg/m³). 0xFFFF indicates invalid.
+	9–10 	0 — 40000 			CO2 (16bit unsigned in ppm). 0xFFFF indicates invalid.
+	11 		0 — 500 			VOC index bits 9–1, bit 0 is in flags. 511 indicates invalid.
+	12 		0 — 500 			NOx index bits 9–1, bit 0 is in flags. 511 indicates invalid.
+	13 		0 — 65535 			Luminosity in lux, logarithmic 8bit encoding. 0xFF indicates invalid.
+	14 		-					Reserved
+	15 		0 — 255 			Measurement sequence number (lowest 8 bits).
+	16 		-					Flags: bit 0 calibration in progress, bit 6 VOC bit 0, bit 7 NOx bit 0
+	17–19 	00:00:00 			Lowest 3 bytes of the MAC address
+*/
+type DataFormat6 struct {
+	ManufacturerID    uint16
+	DataFormat        uint8
+	Temperature       int16
+	Humidity          uint16
+	Pressure          uint16
+	PM25              uint16
+	CO2               uint16
+	VOC               uint8
+	NOX               uint8
+	Luminosity        uint8
+	Reserved          uint8
+	MeasurementNumber uint8
+	Flags             uint8
+	MAC               [3]byte
+}
+
+const (
+	invalidAirIndex   = 511
+	invalidLuminosity = 0xFF
+	maxLuminosityCode = 254
+	maxLuminosity     = 65535
+	flagVOCLowBit     = 6
+	flagNOXLowBit     = 7
+)
+
+func ParseSensorFormat6(data []byte) (sd Data, err error) {
+	reader := bytes.NewReader(data)
+	var result DataFormat6
+	err = binary.Read(reader, binary.BigEndian, &result)
+	if err != nil {
+		return
+	}
+	sd.Temperature = parseTemperature(result.Temperature)
+	sd.Humidity = parseHumidity(result.Humidity)
+	sd.Pressure = parsePressure(result.Pressure)
+	sd.PM25 = parseParticulateMatter(result.PM25)
+	if result.CO2 != invalidUint16 {
+		sd.CO2 = Int(int(result.CO2))
+	}
+	voc := int(result.VOC)<<1 | int(result.Flags>>flagVOCLowBit&1)
+	if voc != invalidAirIndex {
+		sd.VOC = Int(voc)
+	}
+	nox := int(result.NOX)<<1 | int(result.Flags>>flagNOXLowBit&1)
+	if nox != invalidAirIndex {
+		sd.NOX = Int(nox)
+	}
+	if result.Luminosity != invalidLuminosity {
+		sd.Luminosity = Float64(DecodeLuminosity(result.Luminosity))
+	}
+	sd.MeasurementNumber = Int(int(result.MeasurementNumber))
+	return
+}
+
+// DecodeLuminosity decodes the logarithmic 8-bit luminosity value used by data format 6 to lux
+func DecodeLuminosity(code uint8) float64 {
+	coef := maxLuminosityCode / math.Log(maxLuminosity+1)
+	return math.Exp(float64(code)/coef) - 1
+}
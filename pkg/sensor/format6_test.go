@@ -0,0 +1,102 @@
+package sensor
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat6Data(t *testing.T) {
+	tests := []struct {
+		name              string
+		raw               string
+		temperature       *float64
+		humidity          *float64
+		pressure          *float64
+		pm25              *float64
+		co2               *int
+		voc               *int
+		nox               *int
+		luminosity        *float64
+		measurementNumber *int
+	}{
+		{
+			name:              "valid data",
+			raw:               "06170C5668C79E007000C90501D9FFCD004C884F",
+			temperature:       Float64(29.5),
+			humidity:          Float64(55.3),
+			pressure:          Float64(1011.02),
+			pm25:              Float64(11.2),
+			co2:               Int(201),
+			voc:               Int(10),
+			nox:               Int(2),
+			luminosity:        Float64(13027.0),
+			measurementNumber: Int(205),
+		},
+		{
+			name:              "maximum values",
+			raw:               "067FFF9C40FFFE27109C40FAFAFEFFFF004C884F",
+			temperature:       Float64(163.835),
+			humidity:          Float64(100.0),
+			pressure:          Float64(1155.34),
+			pm25:              Float64(1000.0),
+			co2:               Int(40000),
+			voc:               Int(500),
+			nox:               Int(500),
+			luminosity:        Float64(65535.0),
+			measurementNumber: Int(255),
+		},
+		{
+			name:              "minimum values",
+			raw:               "06800100000000000000000000000000004C884F",
+			temperature:       Float64(-163.835),
+			humidity:          Float64(0.0),
+			pressure:          Float64(500.0),
+			pm25:              Float64(0.0),
+			co2:               Int(0),
+			voc:               Int(0),
+			nox:               Int(0),
+			luminosity:        Float64(0.0),
+			measurementNumber: Int(0),
+		},
+		{
+			name:              "invalid values",
+			raw:               "068000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			measurementNumber: Int(255),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := hex.DecodeString(tt.raw)
+			require.NoError(t, err)
+			data, err := Parse(append([]byte{0x99, 0x04}, payload...))
+			require.NoError(t, err)
+			assertFloatPtr(t, tt.temperature, data.Temperature, 0.001)
+			assertFloatPtr(t, tt.humidity, data.Humidity, 0.001)
+			assertFloatPtr(t, tt.pressure, data.Pressure, 0.001)
+			assertFloatPtr(t, tt.pm25, data.PM25, 0.001)
+			assert.Equal(t, tt.co2, data.CO2)
+			assert.Equal(t, tt.voc, data.VOC)
+			assert.Equal(t, tt.nox, data.NOX)
+			assertFloatPtr(t, tt.luminosity, data.Luminosity, 1.0)
+			assert.Equal(t, tt.measurementNumber, data.MeasurementNumber)
+		})
+	}
+}
+
+func TestDecodeLuminosity(t *testing.T) {
+	assert.Equal(t, 0.0, DecodeLuminosity(0))
+	assert.InDelta(t, 65535.0, DecodeLuminosity(254), 0.01)
+}
+
+func assertFloatPtr(t *testing.T, expected, actual *float64, delta float64) {
+	t.Helper()
+	if expected == nil {
+		assert.Nil(t, actual)
+		return
+	}
+	require.NotNil(t, actual)
+	assert.InDelta(t, *expected, *actual, delta)
+}
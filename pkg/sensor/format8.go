@@ -0,0 +1,103 @@
+package sensor
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+/*
+DataFormat8 is the decrypted block of data format 8. The payload is:
+
+	Byte    Value Range			Explanation
+	---------------------------------------
+	0 		08 					Format type code
+	1–16 	-					AES-128-ECB encrypted block, see below
+	17 		0 — 255 			CRC8 of the decrypted block
+	18–23 	00:00:00:...		MAC address
+
+	Decrypted block:
+	0–1 	-163.835 — 163.835 	Temperature (16bit signed in .005 centigrade). 0x8000 indicates invalid.
+	2–3 	0 — 100				Humidity (16bit unsigned in .0025%). 0xFFFF indicates invalid.
+	4–5 	500 — 1155.34 		Atmospheric pressure (16bit unsigned, offset -50000 Pa). 0xFFFF indicates invalid.
+	6–7 	-					Battery voltage and TX power, encoded as in format 5
+	8–9 	0 — 65534 			Movement counter (16bit unsigned). 0xFFFF indicates invalid.
+	10–11 	0 — 65534 			Measurement sequence number (16bit unsigned). 0xFFFF indicates invalid.
+	12–15 	-					Reserved
+*/
+type DataFormat8 struct {
+	Temperature       int16
+	Humidity          uint16
+	Pressure          uint16
+	Power             uint16
+	MovementCounter   uint16
+	MeasurementNumber uint16
+	Reserved          [4]byte
+}
+
+// ErrNoKey is returned when encrypted data is parsed without a decryption key
+var ErrNoKey = errors.New("no decryption key")
+
+// ParseSensorFormat8 decrypts and parses encrypted data format 8 using the given 128-bit AES key
+func ParseSensorFormat8(data []byte, key []byte) (sd Data, err error) {
+	if len(key) == 0 {
+		err = ErrNoKey
+		return
+	}
+	if len(data) < 2+1+aes.BlockSize+1 {
+		err = fmt.Errorf("invalid data length: %d", len(data))
+		return
+	}
+	if len(key) != 16 {
+		err = fmt.Errorf("invalid key length: %d", len(key))
+		return
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	plaintext := make([]byte, aes.BlockSize)
+	block.Decrypt(plaintext, data[3:3+aes.BlockSize])
+	if crc := CRC8(plaintext); crc != data[3+aes.BlockSize] {
+		err = fmt.Errorf("CRC mismatch: expected %#02x, got %#02x", data[3+aes.BlockSize], crc)
+		return
+	}
+	var result DataFormat8
+	err = binary.Read(bytes.NewReader(plaintext), binary.BigEndian, &result)
+	if err != nil {
+		return
+	}
+	sd.Temperature = parseTemperature(result.Temperature)
+	sd.Humidity = parseHumidity(result.Humidity)
+	sd.Pressure = parsePressure(result.Pressure)
+	batteryVoltage := int(result.Power >> 5)
+	if batteryVoltage != 2047 {
+		sd.BatteryVoltage = Float64(float64(batteryVoltage)/1000.0 + 1.6)
+	}
+	sd.TxPower = parseTxPower(result.Power)
+	if result.MovementCounter != invalidUint16 {
+		sd.MovementCounter = Int(int(result.MovementCounter))
+	}
+	if result.MeasurementNumber != invalidUint16 {
+		sd.MeasurementNumber = Int(int(result.MeasurementNumber))
+	}
+	return
+}
+
+// CRC8 calculates the CRC-8 checksum (polynomial 0x07, initial value 0x00) used by RuuviTags
+func CRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,70 @@
+package sensor
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey, _ = hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+
+func encryptFormat8(t *testing.T, key []byte, plaintext string) []byte {
+	t.Helper()
+	block, err := hex.DecodeString(plaintext)
+	require.NoError(t, err)
+	require.Len(t, block, aes.BlockSize)
+	c, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	encrypted := make([]byte, aes.BlockSize)
+	c.Encrypt(encrypted, block)
+	data := []byte{0x99, 0x04, 0x08}
+	data = append(data, encrypted...)
+	data = append(data, CRC8(block))
+	return append(data, 0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F)
+}
+
+func TestCRC8(t *testing.T) {
+	assert.Equal(t, byte(0xF4), CRC8([]byte("123456789")))
+}
+
+func TestParseFormat8Data(t *testing.T) {
+	data := encryptFormat8(t, testKey, "12FC5394C37CAC360042CDCD00000000")
+	sd, err := ParseWithKey(data, testKey)
+	require.NoError(t, err)
+	assertFloatPtr(t, Float64(24.3), sd.Temperature, 0.001)
+	assertFloatPtr(t, Float64(53.49), sd.Humidity, 0.001)
+	assertFloatPtr(t, Float64(1000.44), sd.Pressure, 0.001)
+	assertFloatPtr(t, Float64(2.977), sd.BatteryVoltage, 0.001)
+	assert.Equal(t, Int(4), sd.TxPower)
+	assert.Equal(t, Int(66), sd.MovementCounter)
+	assert.Equal(t, Int(52685), sd.MeasurementNumber)
+}
+
+func TestParseFormat8InvalidValues(t *testing.T) {
+	data := encryptFormat8(t, testKey, "8000FFFFFFFFFFFFFFFFFFFF00000000")
+	sd, err := ParseWithKey(data, testKey)
+	require.NoError(t, err)
+	assert.Nil(t, sd.Temperature)
+	assert.Nil(t, sd.Humidity)
+	assert.Nil(t, sd.Pressure)
+	assert.Nil(t, sd.BatteryVoltage)
+	assert.Nil(t, sd.TxPower)
+	assert.Nil(t, sd.MovementCounter)
+	assert.Nil(t, sd.MeasurementNumber)
+}
+
+func TestParseFormat8WrongKey(t *testing.T) {
+	data := encryptFormat8(t, testKey, "12FC5394C37CAC360042CDCD00000000")
+	wrongKey, _ := hex.DecodeString("0F0E0D0C0B0A09080706050403020100")
+	_, err := ParseWithKey(data, wrongKey)
+	assert.ErrorContains(t, err, "CRC mismatch")
+}
+
+func TestParseFormat8WithoutKey(t *testing.T) {
+	data := encryptFormat8(t, testKey, "12FC5394C37CAC360042CDCD00000000")
+	_, err := Parse(data)
+	assert.ErrorIs(t, err, ErrNoKey)
+}
@@ -0,0 +1,118 @@
+package sensor
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+/*
+DataFormatE1 is the payload of the extended data format E1 (Ruuvi Air):
+
+	Byte    Value Range			Explanation
+	---------------------------------------
+	0 		E1 					Format type code
+	1–2 	-163.835 — 163.835 	Temperature (16bit signed in .005 centigrade). 0x8000 indicates invalid.
+	3–4 	0 — 100				Humidity (16bit unsigned in .0025%). 0xFFFF indicates invalid.
+	5–6 	500 — 1155.34 		Atmospheric pressure (16bit unsigned, offset -50000 Pa). 0xFFFF indicates invalid.
+	7–8 	0 — 1000 			PM1.0 (16bit unsigned in 0.1 µg/m³). 0xFFFF indicates invalid.
+	9–10 	0 — 1000 			PM2.5 (16bit unsigned in 0.1 µg/m³). 0xFFFF indicates invalid.
+	11–12 	0 — 1000 			PM4.0 (16bit unsigned in 0.1 µg/m³). 0xFFFF indicates invalid.
+	13–14 	0 — 1000 			PM10.0 (16bit unsigned in 0.1 µg/m³). 0xFFFF indicates invalid.
+	15–16 	0 — 40000 			CO2 (16bit unsigned in ppm). 0xFFFF indicates invalid.
+	17 		0 — 500 			VOC index bits 9–1, bit 0 is in flags. 511 indicates invalid.
+	18 		0 — 500 			NOx index bits 9–1, bit 0 is in flags. 511 indicates invalid.
+	19–21 	0 — 144284 			Luminosity (24bit unsigned in 0.01 lux). 0xFFFFFF indicates invalid.
+	22 		18 — 120 			Instant sound level bits 9–1 in 0.2 dBA steps, offset 18 dBA. 511 indicates invalid.
+	23 		18 — 120 			Average sound level bits 9–1 in 0.2 dBA steps, offset 18 dBA. 511 indicates invalid.
+	24 		18 — 120 			Peak sound level bits 9–1 in 0.2 dBA steps, offset 18 dBA. 511 indicates invalid.
+	25–27 	0 — 16777214 		Measurement sequence number (24bit unsigned). 0xFFFFFF indicates invalid.
+	28 		-					Flags: bit 0 calibration in progress, bits 3–5 instant, average and peak sound bit 0, bit 6 VOC bit 0, bit 7 NOx bit 0
+	29–33 	-					Reserved
+	34–39 	00:00:00:00:00:00 	MAC address
+*/
+type DataFormatE1 struct {
+	ManufacturerID    uint16
+	DataFormat        uint8
+	Temperature       int16
+	Humidity          uint16
+	Pressure          uint16
+	PM1               uint16
+	PM25              uint16
+	PM4               uint16
+	PM10              uint16
+	CO2               uint16
+	VOC               uint8
+	NOX               uint8
+	Luminosity        [3]byte
+	SoundInstant      uint8
+	SoundAverage      uint8
+	SoundPeak         uint8
+	MeasurementNumber [3]byte
+	Flags             uint8
+	Reserved          [5]byte
+	MAC               [6]byte
+}
+
+const (
+	invalidUint24          = 0xFFFFFF
+	invalidSoundLevel      = 511
+	flagSoundInstantLowBit = 3
+	flagSoundAverageLowBit = 4
+	flagSoundPeakLowBit    = 5
+)
+
+func ParseSensorFormatE1(data []byte) (sd Data, err error) {
+	reader := bytes.NewReader(data)
+	var result DataFormatE1
+	err = binary.Read(reader, binary.BigEndian, &result)
+	if err != nil {
+		return
+	}
+	sd.Temperature = parseTemperature(result.Temperature)
+	sd.Humidity = parseHumidity(result.Humidity)
+	sd.Pressure = parsePressure(result.Pressure)
+	sd.PM1 = parseParticulateMatter(result.PM1)
+	sd.PM25 = parseParticulateMatter(result.PM25)
+	sd.PM4 = parseParticulateMatter(result.PM4)
+	sd.PM10 = parseParticulateMatter(result.PM10)
+	if result.CO2 != invalidUint16 {
+		sd.CO2 = Int(int(result.CO2))
+	}
+	voc := int(result.VOC)<<1 | int(result.Flags>>flagVOCLowBit&1)
+	if voc != invalidAirIndex {
+		sd.VOC = Int(voc)
+	}
+	nox := int(result.NOX)<<1 | int(result.Flags>>flagNOXLowBit&1)
+	if nox != invalidAirIndex {
+		sd.NOX = Int(nox)
+	}
+	if luminosity := uint24(result.Luminosity); luminosity != invalidUint24 {
+		sd.Luminosity = Float64(float64(luminosity) / 100.0)
+	}
+	sd.SoundInstant = parseSoundLevel(result.SoundInstant, result.Flags, flagSoundInstantLowBit)
+	sd.SoundAverage = parseSoundLevel(result.SoundAverage, result.Flags, flagSoundAverageLowBit)
+	sd.SoundPeak = parseSoundLevel(result.SoundPeak, result.Flags, flagSoundPeakLowBit)
+	if measurementNumber := uint24(result.MeasurementNumber); measurementNumber != invalidUint24 {
+		sd.MeasurementNumber = Int(int(measurementNumber))
+	}
+	return
+}
+
+func parseParticulateMatter(v uint16) *float64 {
+	if v == invalidUint16 {
+		return nil
+	}
+	return Float64(float64(v) / 10.0)
+}
+
+func parseSoundLevel(v uint8, flags uint8, lowBit int) *float64 {
+	level := int(v)<<1 | int(flags>>lowBit&1)
+	if level == invalidSoundLevel {
+		return nil
+	}
+	return Float64(float64(level)*0.2 + 18.0)
+}
+
+func uint24(b [3]byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
@@ -0,0 +1,112 @@
+package sensor
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormatE1Data(t *testing.T) {
+	tests := []struct {
+		name              string
+		raw               string
+		temperature       *float64
+		humidity          *float64
+		pressure          *float64
+		pm1               *float64
+		pm25              *float64
+		pm4               *float64
+		pm10              *float64
+		co2               *int
+		voc               *int
+		nox               *int
+		luminosity        *float64
+		soundInstant      *float64
+		soundAverage      *float64
+		soundPeak         *float64
+		measurementNumber *int
+	}{
+		{
+			name:              "valid data",
+			raw:               "E1170C5668C79E0065007004BD11CA00C90A0213E0AC4A528EDECDEE30FFFFFFFFFFCBB8334C884F",
+			temperature:       Float64(29.5),
+			humidity:          Float64(55.3),
+			pressure:          Float64(1011.02),
+			pm1:               Float64(10.1),
+			pm25:              Float64(11.2),
+			pm4:               Float64(121.3),
+			pm10:              Float64(455.4),
+			co2:               Int(201),
+			voc:               Int(20),
+			nox:               Int(4),
+			luminosity:        Float64(13027.0),
+			soundInstant:      Float64(47.6),
+			soundAverage:      Float64(51.0),
+			soundPeak:         Float64(75.0),
+			measurementNumber: Int(14601710),
+		},
+		{
+			name:              "maximum values",
+			raw:               "E17FFF9C40FFFE27102710271027109C40FAFADC28F0FEFEFEFFFFFE00FFFFFFFFFFCBB8334C884F",
+			temperature:       Float64(163.835),
+			humidity:          Float64(100.0),
+			pressure:          Float64(1155.34),
+			pm1:               Float64(1000.0),
+			pm25:              Float64(1000.0),
+			pm4:               Float64(1000.0),
+			pm10:              Float64(1000.0),
+			co2:               Int(40000),
+			voc:               Int(500),
+			nox:               Int(500),
+			luminosity:        Float64(144284.0),
+			soundInstant:      Float64(119.6),
+			soundAverage:      Float64(119.6),
+			soundPeak:         Float64(119.6),
+			measurementNumber: Int(16777214),
+		},
+		{
+			name: "invalid values",
+			raw:  "E18000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFCBB8334C884F",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := hex.DecodeString(tt.raw)
+			require.NoError(t, err)
+			data, err := Parse(append([]byte{0x99, 0x04}, payload...))
+			require.NoError(t, err)
+			assertFloatPtr(t, tt.temperature, data.Temperature, 0.001)
+			assertFloatPtr(t, tt.humidity, data.Humidity, 0.001)
+			assertFloatPtr(t, tt.pressure, data.Pressure, 0.001)
+			assertFloatPtr(t, tt.pm1, data.PM1, 0.001)
+			assertFloatPtr(t, tt.pm25, data.PM25, 0.001)
+			assertFloatPtr(t, tt.pm4, data.PM4, 0.001)
+			assertFloatPtr(t, tt.pm10, data.PM10, 0.001)
+			assert.Equal(t, tt.co2, data.CO2)
+			assert.Equal(t, tt.voc, data.VOC)
+			assert.Equal(t, tt.nox, data.NOX)
+			assertFloatPtr(t, tt.luminosity, data.Luminosity, 0.001)
+			assertFloatPtr(t, tt.soundInstant, data.SoundInstant, 0.001)
+			assertFloatPtr(t, tt.soundAverage, data.SoundAverage, 0.001)
+			assertFloatPtr(t, tt.soundPeak, data.SoundPeak, 0.001)
+			assert.Equal(t, tt.measurementNumber, data.MeasurementNumber)
+		})
+	}
+}
+
+func TestParseTruncatedFormatE1Data(t *testing.T) {
+	payload, err := hex.DecodeString("E1170C5668C79E006500700079008200C9")
+	require.NoError(t, err)
+	data := append([]byte{0x99, 0x04}, payload...)
+	assert.True(t, IsRuuviTag(data))
+	_, err = Parse(data)
+	assert.Error(t, err)
+}
+
+func TestLegacyFormatsStillRecognized(t *testing.T) {
+	assert.True(t, IsRuuviTag(testData))
+	assert.False(t, IsRuuviTag([]byte{0x99, 0x04}))
+	assert.False(t, IsRuuviTag([]byte{0x4C, 0x00, 0x05}))
+}
@@ -6,6 +6,11 @@ import (
 )
 
 func Parse(data []byte) (sensorData Data, err error) {
+	return ParseWithKey(data, nil)
+}
+
+// ParseWithKey parses RuuviTag data and decrypts it with the given key if the data is encrypted
+func ParseWithKey(data []byte, key []byte) (sensorData Data, err error) {
 	if !IsRuuviTag(data) {
 		err = fmt.Errorf("not a RuuviTag device")
 		return
@@ -18,12 +23,24 @@ func Parse(data []byte) (sensorData Data, err error) {
 	case 5:
 		sensorData, err = ParseSensorFormat5(data)
 		return
+	case 6:
+		sensorData, err = ParseSensorFormat6(data)
+		return
+	case 8:
+		sensorData, err = ParseSensorFormat8(data, key)
+		return
+	case 0xE1:
+		sensorData, err = ParseSensorFormatE1(data)
+		return
 	default:
 		err = fmt.Errorf("unknown sensor format: %v", sensorFormat)
 		return
 	}
 }
 
+// IsRuuviTag checks whether the given manufacturer data has the Ruuvi manufacturer ID and a data format byte.
+// The length of the payload is validated by the parser of each data format since extended formats such as E1
+// are longer than the legacy ones.
 func IsRuuviTag(data []byte) bool {
-	return len(data) >= 16 && binary.BigEndian.Uint16(data[0:2]) == 0x9904
+	return len(data) >= 3 && binary.BigEndian.Uint16(data[0:2]) == 0x9904
 }
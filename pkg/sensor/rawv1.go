@@ -3,6 +3,7 @@ package sensor
 import (
 	"bytes"
 	"encoding/binary"
+	"math"
 
 	"github.com/niktheblak/ruuvitag-gollector/pkg/dewpoint"
 	"github.com/niktheblak/ruuvitag-gollector/pkg/temperature"
@@ -39,13 +40,79 @@ func ParseSensorFormat3(data []byte) (sd Data, err error) {
 	if err != nil {
 		return
 	}
-	sd.Temperature = ParseTemperature(result.Temperature, result.TemperatureFraction)
-	sd.Humidity = float64(result.Humidity) / 2.0
-	sd.DewPoint, _ = dewpoint.Calculate(sd.Temperature, temperature.Celsius, sd.Humidity)
-	sd.Pressure = float64(int(result.Pressure)+50000) / 100.0
-	sd.BatteryVoltage = float64(result.BatteryVoltageMv)
-	sd.AccelerationX = int(result.AccelerationX)
-	sd.AccelerationY = int(result.AccelerationY)
-	sd.AccelerationZ = int(result.AccelerationZ)
+	temp := ParseTemperature(result.Temperature, result.TemperatureFraction)
+	humidity := float64(result.Humidity) / 2.0
+	sd.Temperature = Float64(temp)
+	sd.Humidity = Float64(humidity)
+	if dp, err := dewpoint.Calculate(temp, temperature.Celsius, humidity); err == nil {
+		sd.DewPoint = Float64(dp)
+	}
+	sd.Pressure = Float64(float64(int(result.Pressure)+50000) / 100.0)
+	sd.BatteryVoltage = Float64(float64(result.BatteryVoltageMv))
+	sd.AccelerationX = Int(int(result.AccelerationX))
+	sd.AccelerationY = Int(int(result.AccelerationY))
+	sd.AccelerationZ = Int(int(result.AccelerationZ))
+	return
+}
+
+// EncodeSensorFormat3 encodes the sensor data to data format 3 (RAWv1). The format has no way to
+// indicate missing values so nil fields are encoded as zero and values out of range are clamped.
+// Battery voltage is interpreted in millivolts like ParseSensorFormat3 reports it.
+func EncodeSensorFormat3(sd Data) []byte {
+	result := DataFormat3{
+		ManufacturerID: 0x9904,
+		DataFormat:     3,
+	}
+	if sd.Humidity != nil {
+		result.Humidity = uint8(clamp(roundToInt(*sd.Humidity*2.0), 0, math.MaxUint8))
+	}
+	if sd.Temperature != nil {
+		result.Temperature, result.TemperatureFraction = EncodeTemperature(*sd.Temperature)
+	}
+	if sd.Pressure != nil {
+		result.Pressure = uint16(clamp(roundToInt(*sd.Pressure*100.0)-50000, 0, math.MaxUint16))
+	}
+	result.AccelerationX = int16(clamp(intValue(sd.AccelerationX), math.MinInt16, math.MaxInt16))
+	result.AccelerationY = int16(clamp(intValue(sd.AccelerationY), math.MinInt16, math.MaxInt16))
+	result.AccelerationZ = int16(clamp(intValue(sd.AccelerationZ), math.MinInt16, math.MaxInt16))
+	if sd.BatteryVoltage != nil {
+		result.BatteryVoltageMv = uint16(clamp(roundToInt(*sd.BatteryVoltage), 0, math.MaxUint16))
+	}
+	buf := new(bytes.Buffer)
+	// Writing a fixed size struct to a bytes.Buffer cannot fail
+	_ = binary.Write(buf, binary.BigEndian, result)
+	return buf.Bytes()
+}
+
+// EncodeTemperature encodes the temperature to the sign and magnitude integer part and the fraction
+// in hundredths used by data format 3. It is the inverse of ParseTemperature.
+func EncodeTemperature(temp float64) (t uint8, f uint8) {
+	cents := clamp(roundToInt(math.Abs(temp)*100.0), 0, 127*100+99)
+	t = uint8(cents / 100)
+	f = uint8(cents % 100)
+	if temp < 0 && cents > 0 {
+		t |= 1 << 7
+	}
 	return
 }
+
+func roundToInt(v float64) int {
+	if math.IsNaN(v) {
+		return 0
+	}
+	r := math.Round(v)
+	if r > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if r < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int(r)
+}
+
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
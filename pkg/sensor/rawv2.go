@@ -34,6 +34,12 @@ byte 14&1F	-40 — +20 		TX power above -40dBm, in 2dBm steps. 5 bits unsigned.
 16–17 	0 — 65,534 			Measurement sequence number (16bit unsigned).
 18–23 	00:00:00:...		-
 */
+const (
+	invalidTemperature  = -0x8000
+	invalidAcceleration = -0x8000
+	invalidUint16       = 0xFFFF
+)
+
 type DataFormat5 struct {
 	ManufacturerID    uint16
 	DataFormat        uint8
@@ -55,21 +61,123 @@ func ParseSensorFormat5(data []byte) (sd Data, err error) {
 	if err != nil {
 		return
 	}
-	sd.Temperature = float64(result.Temperature) * 0.005
-	sd.Humidity = float64(result.Humidity) / 400.0
-	sd.Pressure = float64(int(result.Pressure)+50000) / 100.0
-	sd.AccelerationX = int(result.AccelerationX)
-	sd.AccelerationY = int(result.AccelerationY)
-	sd.AccelerationZ = int(result.AccelerationZ)
+	sd.Temperature = parseTemperature(result.Temperature)
+	sd.Humidity = parseHumidity(result.Humidity)
+	sd.Pressure = parsePressure(result.Pressure)
+	sd.AccelerationX = parseAcceleration(result.AccelerationX)
+	sd.AccelerationY = parseAcceleration(result.AccelerationY)
+	sd.AccelerationZ = parseAcceleration(result.AccelerationZ)
 	batteryVoltage := int(result.Power >> 5)
 	if batteryVoltage != 2047 {
-		sd.BatteryVoltage = float64(batteryVoltage)/1000.0 + 1.6
+		sd.BatteryVoltage = Float64(float64(batteryVoltage)/1000.0 + 1.6)
+	}
+	sd.TxPower = parseTxPower(result.Power)
+	if result.MovementCounter != 0xFF {
+		sd.MovementCounter = Int(int(result.MovementCounter))
 	}
-	txPower := int(result.Power & 0x1F)
-	if txPower != 0x1F {
-		sd.TxPower = txPower - 40
+	if result.MeasurementNumber != invalidUint16 {
+		sd.MeasurementNumber = Int(int(result.MeasurementNumber))
 	}
-	sd.MovementCounter = int(result.MovementCounter)
-	sd.MeasurementNumber = int(result.MeasurementNumber)
 	return
 }
+
+// EncodeSensorFormat5 encodes the sensor data to data format 5 (RAWv2). Nil fields and values that
+// cannot be represented in the format are encoded with the invalid value of the field.
+func EncodeSensorFormat5(sd Data) []byte {
+	result := DataFormat5{
+		ManufacturerID:    0x9904,
+		DataFormat:        5,
+		Temperature:       invalidTemperature,
+		Humidity:          invalidUint16,
+		Pressure:          invalidUint16,
+		AccelerationX:     encodeAcceleration(sd.AccelerationX),
+		AccelerationY:     encodeAcceleration(sd.AccelerationY),
+		AccelerationZ:     encodeAcceleration(sd.AccelerationZ),
+		MovementCounter:   0xFF,
+		MeasurementNumber: invalidUint16,
+	}
+	if sd.Temperature != nil {
+		if v, ok := scale(*sd.Temperature, 200.0, invalidTemperature+1, 0x7FFF); ok {
+			result.Temperature = int16(v)
+		}
+	}
+	if sd.Humidity != nil {
+		if v, ok := scale(*sd.Humidity, 400.0, 0, invalidUint16-1); ok {
+			result.Humidity = uint16(v)
+		}
+	}
+	if sd.Pressure != nil {
+		if v, ok := scale(*sd.Pressure-500.0, 100.0, 0, invalidUint16-1); ok {
+			result.Pressure = uint16(v)
+		}
+	}
+	batteryVoltage := 2047
+	if sd.BatteryVoltage != nil {
+		if v, ok := scale(*sd.BatteryVoltage-1.6, 1000.0, 0, 2046); ok {
+			batteryVoltage = v
+		}
+	}
+	result.Power = uint16(batteryVoltage<<5 | encodeTxPower(sd.TxPower))
+	if sd.MovementCounter != nil && *sd.MovementCounter >= 0 && *sd.MovementCounter < 0xFF {
+		result.MovementCounter = uint8(*sd.MovementCounter)
+	}
+	if sd.MeasurementNumber != nil && *sd.MeasurementNumber >= 0 && *sd.MeasurementNumber < invalidUint16 {
+		result.MeasurementNumber = uint16(*sd.MeasurementNumber)
+	}
+	buf := new(bytes.Buffer)
+	// Writing a fixed size struct to a bytes.Buffer cannot fail
+	_ = binary.Write(buf, binary.BigEndian, result)
+	return append(buf.Bytes(), macAddress(sd.Addr)...)
+}
+
+func encodeAcceleration(v *int) int16 {
+	if v == nil || *v <= invalidAcceleration || *v > 0x7FFF {
+		return invalidAcceleration
+	}
+	return int16(*v)
+}
+
+// parseTxPower parses the transmit power from the low 5 bits of the power field of formats 5 and 8. The
+// transmit power is sent in 2 dBm steps from -40 dBm.
+func parseTxPower(power uint16) *int {
+	v := int(power & 0x1F)
+	if v == 0x1F {
+		return nil
+	}
+	return Int(v*2 - 40)
+}
+
+func encodeTxPower(v *int) int {
+	if v == nil || *v < -40 || *v%2 != 0 || (*v+40)/2 >= 0x1F {
+		return 0x1F
+	}
+	return (*v + 40) / 2
+}
+
+func parseTemperature(v int16) *float64 {
+	if v == invalidTemperature {
+		return nil
+	}
+	return Float64(float64(v) * 0.005)
+}
+
+func parseHumidity(v uint16) *float64 {
+	if v == invalidUint16 {
+		return nil
+	}
+	return Float64(float64(v) / 400.0)
+}
+
+func parsePressure(v uint16) *float64 {
+	if v == invalidUint16 {
+		return nil
+	}
+	return Float64(float64(int(v)+50000) / 100.0)
+}
+
+func parseAcceleration(v int16) *int {
+	if v == invalidAcceleration {
+		return nil
+	}
+	return Int(int(v))
+}
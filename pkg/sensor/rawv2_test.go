@@ -1,6 +1,7 @@
 package sensor
 
 import (
+	"encoding/hex"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,14 +21,31 @@ func TestRAWv2Format(t *testing.T) {
 func TestParseRAWv2Data(t *testing.T) {
 	data, err := Parse(testData)
 	require.NoError(t, err)
-	assert.Equal(t, 24.1, data.Temperature)
-	assert.Equal(t, 100.0, data.Humidity)
-	assert.Equal(t, 999.84, data.Pressure)
-	assert.Equal(t, 2.755, data.BatteryVoltage)
-	assert.Equal(t, -18, data.TxPower)
-	assert.Equal(t, 56, data.AccelerationX)
-	assert.Equal(t, 228, data.AccelerationY)
-	assert.Equal(t, 996, data.AccelerationZ)
-	assert.Equal(t, 65, data.MovementCounter)
-	assert.Equal(t, 44526, data.MeasurementNumber)
+	assert.Equal(t, Float64(24.1), data.Temperature)
+	assert.Equal(t, Float64(100.0), data.Humidity)
+	assert.Equal(t, Float64(999.84), data.Pressure)
+	assert.Equal(t, Float64(2.755), data.BatteryVoltage)
+	assert.Equal(t, Int(4), data.TxPower)
+	assert.Equal(t, Int(56), data.AccelerationX)
+	assert.Equal(t, Int(228), data.AccelerationY)
+	assert.Equal(t, Int(996), data.AccelerationZ)
+	assert.Equal(t, Int(65), data.MovementCounter)
+	assert.Equal(t, Int(44526), data.MeasurementNumber)
+}
+
+func TestParseRAWv2InvalidData(t *testing.T) {
+	payload, err := hex.DecodeString("058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF")
+	require.NoError(t, err)
+	data, err := Parse(append([]byte{0x99, 0x04}, payload...))
+	require.NoError(t, err)
+	assert.Nil(t, data.Temperature)
+	assert.Nil(t, data.Humidity)
+	assert.Nil(t, data.Pressure)
+	assert.Nil(t, data.BatteryVoltage)
+	assert.Nil(t, data.TxPower)
+	assert.Nil(t, data.AccelerationX)
+	assert.Nil(t, data.AccelerationY)
+	assert.Nil(t, data.AccelerationZ)
+	assert.Nil(t, data.MovementCounter)
+	assert.Nil(t, data.MeasurementNumber)
 }